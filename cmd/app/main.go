@@ -3,15 +3,18 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	stdrt "runtime"
+	"slug/internal/ast"
 	"slug/internal/lexer"
 	"slug/internal/object"
 	"slug/internal/parser"
 	"slug/internal/runtime"
 	"slug/internal/util"
+	"sort"
 	"strings"
 )
 
@@ -28,6 +31,13 @@ var (
 	rootPath     string
 	debugJsonAST bool
 	debugTxtAST  bool
+	seed         int64
+	precision    int
+	concurrency  int
+	strict       bool
+	modulePath   string
+	testMode     bool
+	checkMode    bool
 )
 
 func init() {
@@ -37,6 +47,13 @@ func init() {
 	flag.BoolVar(&version, "v", false, "Display version information and exit")
 	// evaluator config
 	flag.StringVar(&rootPath, "root", "", "Set the root context for the program (used for imports)")
+	flag.StringVar(&modulePath, "module-path", "", "Extra module search paths, separated by the OS path list separator (':' on Unix)")
+	flag.BoolVar(&testMode, "test", false, "Run the top-level @test functions in the script and report pass/fail")
+	flag.BoolVar(&checkMode, "check", false, "Parse the script and report errors, such as undefined names, without running it")
+	flag.Int64Var(&seed, "seed", 0, "Seed the random source for reproducible runs (0 uses the clock)")
+	flag.IntVar(&precision, "precision", 0, "Round numbers to this many decimal places when printed (0 prints them in full)")
+	flag.BoolVar(&strict, "strict", false, "Make out of range indexes and unmatched match expressions errors instead of nil")
+	flag.IntVar(&concurrency, "concurrency", max(stdrt.NumCPU()*2, 4), "Default number of tasks a nursery runs at once")
 	// parser config
 	flag.BoolVar(&debugJsonAST, "debug-json-ast", false, "Render the AST as a JSON file")
 	flag.BoolVar(&debugTxtAST, "debug-txt-ast", false, "Render the AST as a TXT file")
@@ -54,16 +71,31 @@ func main() {
 		return
 	}
 
-	if help || flag.NArg() == 0 {
+	if help || (flag.NArg() == 0 && !stdinIsPiped(os.Stdin)) {
 		printHelp()
 		return
 	}
 
+	if err := validateFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	setupLogging()
 
-	// 1. Resolve Script Path
+	// 1. Resolve Script Path, a program piped to stdin runs as if it were a file in the working directory
 	targetName := flag.Arg(0)
-	scriptPath, source, resolvedRootPath, err := resolveScript(targetName)
+	var scriptPath, resolvedRootPath string
+	var source []byte
+	var err error
+	if flag.NArg() == 0 {
+		targetName = stdinModule
+		scriptPath = stdinPath
+		resolvedRootPath, _ = os.Getwd()
+		source, err = io.ReadAll(os.Stdin)
+	} else {
+		scriptPath, source, resolvedRootPath, err = resolveScript(targetName)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -77,7 +109,7 @@ func main() {
 
 	// Normalize paths
 	normalizedTargetName := targetName
-	if absTarget, err := filepath.Abs(targetName); err == nil {
+	if absTarget, err := filepath.Abs(targetName); err == nil && targetName != stdinModule {
 		normalizedTargetName = filepath.Clean(absTarget)
 	}
 	resolvedRootPath = filepath.Clean(resolvedRootPath)
@@ -88,28 +120,79 @@ func main() {
 		mainModule = strings.TrimSuffix(mainModule, ".slug")
 	}
 
-	config := util.Configuration{
-		Version:      Version,
-		RootPath:     resolvedRootPath,
-		SlugHome:     os.Getenv("SLUG_HOME"),
-		DebugJsonAST: debugJsonAST,
-		DebugTxtAST:  debugTxtAST,
-		DefaultLimit: max(stdrt.NumCPU()*2, 4),
-		Argv:         flag.Args()[1:],
-		MainModule:   mainModule,
+	config := newConfiguration(resolvedRootPath, mainModule)
+	code := 0
+	if checkMode {
+		code = check(scriptPath, source, config, os.Stderr)
+	} else {
+		code = run(scriptPath, source, config)
+	}
+	if code != 0 {
+		os.Exit(code)
 	}
+}
 
-	// 3. Tokenize & Parse
+// parseScript parses a program, writing any errors to w. It returns nil when
+// the program has errors. Parsing also rejects recur outside tail position.
+func parseScript(scriptPath string, source []byte, w io.Writer) *ast.Program {
 	l := lexer.New(string(source))
 	p := parser.New(l, scriptPath, string(source))
 	program := p.ParseProgram()
 
 	if len(p.Errors()) != 0 {
-		fmt.Fprintf(os.Stderr, "Parse errors:\n")
+		fmt.Fprintf(w, "Parse errors:\n")
 		for _, msg := range p.Errors() {
-			fmt.Fprintf(os.Stderr, "\t%s\n", msg)
+			fmt.Fprintf(w, "\t%s\n", msg)
 		}
-		os.Exit(1)
+		return nil
+	}
+	return program
+}
+
+// check parses a program without running it and reports identifiers it never
+// defines, plus unused bindings and unreachable code as warnings. It returns the exit code,
+// which only errors make non-zero.
+func check(scriptPath string, source []byte, config util.Configuration, w io.Writer) int {
+	program := parseScript(scriptPath, source, w)
+	if program == nil {
+		return 1
+	}
+
+	rt := runtime.NewRuntime(config)
+	resolver := &parser.Resolver{ModuleNames: rt.ModuleNames}
+	for name := range rt.Builtins {
+		resolver.Globals = append(resolver.Globals, name)
+	}
+
+	warnings := append(resolver.Unused(program), resolver.Unreachable(program)...)
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Position < warnings[j].Position })
+	if len(warnings) > 0 {
+		fmt.Fprintf(w, "Check warnings:\n")
+		for _, d := range warnings {
+			fmt.Fprintf(w, "\t\n%s\n", util.FormatDiagnostic(string(source), scriptPath, d.Position, "Warning: "+d.Message))
+		}
+	}
+
+	diagnostics := resolver.Undefined(program)
+	if len(diagnostics) == 0 {
+		return 0
+	}
+	fmt.Fprintf(w, "Check errors:\n")
+	for _, d := range diagnostics {
+		fmt.Fprintf(w, "\t\n%s\n", util.FormatDiagnostic(string(source), scriptPath, d.Position, "NameError: "+d.Message))
+	}
+	return 1
+}
+
+// run parses and evaluates a program as the main module, then runs its @test
+// functions in test mode. It returns the process exit code.
+func run(scriptPath string, source []byte, config util.Configuration) int {
+	mainModule := config.MainModule
+
+	// 3. Tokenize & Parse
+	program := parseScript(scriptPath, source, os.Stderr)
+	if program == nil {
+		return 1
 	}
 
 	// 5. Initialize Task & Environment
@@ -154,11 +237,36 @@ func main() {
 	if result != nil {
 		if result.Type() == object.ERROR_OBJ {
 			fmt.Fprintf(os.Stderr, "Slug Error:\n%s\n", result.Inspect())
-			os.Exit(1)
+			return 1
 		}
 		// In non-REPL mode, we usually don't print the final expression result
 		// unless it's an error, but you can if you want to.
 	}
+
+	// 8. Run @test functions in test mode
+	if testMode {
+		results, err := eval.RunTests(mainModule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if runtime.WriteTestReport(os.Stdout, results) > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+const (
+	// stdinModule and stdinPath name a program piped to stdin in module lookups and stack traces.
+	stdinModule = "<main>"
+	stdinPath   = "<stdin>"
+)
+
+// stdinIsPiped reports whether f is a pipe or file rather than a terminal.
+func stdinIsPiped(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
 }
 
 func resolveScript(target string) (string, []byte, string, error) {
@@ -236,13 +344,57 @@ func configureLogWriter() *os.File {
 	return logWriter
 }
 
+// validateFlags rejects flag values that parse but make no sense.
+func validateFlags() error {
+	if concurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1, got %d", concurrency)
+	}
+	return nil
+}
+
+// newConfiguration builds the runtime configuration from the parsed flags.
+func newConfiguration(rootPath, mainModule string) util.Configuration {
+	return util.Configuration{
+		Version:          Version,
+		RootPath:         rootPath,
+		ModulePaths:      filepath.SplitList(modulePath),
+		SlugHome:         os.Getenv("SLUG_HOME"),
+		DebugJsonAST:     debugJsonAST,
+		DebugTxtAST:      debugTxtAST,
+		DefaultLimit:     concurrency,
+		Seed:             seed,
+		Argv:             scriptArgs(),
+		DisplayPrecision: precision,
+		MainModule:       mainModule,
+		StrictIndex:      strict,
+		StrictMatch:      strict,
+	}
+}
+
+// scriptArgs are the arguments after the script name, or all of them when the
+// script is read from stdin.
+func scriptArgs() []string {
+	if flag.NArg() == 0 {
+		return []string{}
+	}
+	return flag.Args()[1:]
+}
+
 func printHelp() {
 	fmt.Printf(`Slug — No Shell. All Strength.
 
 Usage: slug [options] <filename> <args>
+       slug [options] < program.slug
 
 Options:
   -root <path>       Set the root context
+  -module-path <dir> Extra module search paths, separated by ':' (';' on Windows)
+  -test              Run the script's top-level @test functions and report pass/fail
+  -check             Parse the script and report errors, such as undefined names, without running it
+  -strict            Make out of range indexes and unmatched match expressions errors
+  -concurrency <n>   Default number of tasks a nursery runs at once (default 2 x CPUs, at least 4)
+  -precision <n>     Round numbers to n decimal places in print and println output (0 prints them in full)
+  -seed <n>          Seed the random source used by uuid for reproducible runs (0 uses the clock)
   -version, -v       Show version
   -help, -h          Show this help
   -log-source        Include the source file name in log messages.
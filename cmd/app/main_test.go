@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConcurrencyFlagSetsDefaultLimit(t *testing.T) {
+	defer func(old int) { concurrency = old }(concurrency)
+
+	if err := flag.CommandLine.Parse([]string{"-concurrency", "3", "script.slug", "arg"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := validateFlags(); err != nil {
+		t.Fatalf("expected -concurrency 3 to be valid, got %v", err)
+	}
+
+	config := newConfiguration("/root", "script")
+	if config.DefaultLimit != 3 {
+		t.Fatalf("expected DefaultLimit 3, got %d", config.DefaultLimit)
+	}
+	if len(config.Argv) != 1 || config.Argv[0] != "arg" {
+		t.Fatalf("expected script arguments to follow the script name, got %v", config.Argv)
+	}
+
+	if config.StrictIndex || config.StrictMatch {
+		t.Fatalf("expected strict modes to be off by default")
+	}
+
+	for _, bad := range []string{"0", "-2"} {
+		if err := flag.CommandLine.Parse([]string{"-concurrency", bad, "script.slug"}); err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if validateFlags() == nil {
+			t.Errorf("expected -concurrency %s to be rejected", bad)
+		}
+	}
+}
+
+func TestStrictFlagEnablesStrictModes(t *testing.T) {
+	defer func(old bool) { strict = old }(strict)
+
+	if err := flag.CommandLine.Parse([]string{"-strict", "script.slug"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	config := newConfiguration("/root", "script")
+	if !config.StrictIndex || !config.StrictMatch {
+		t.Fatalf("expected -strict to enable StrictIndex and StrictMatch, got %+v", config)
+	}
+}
+
+func TestPipedStdinRuns(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	if !stdinIsPiped(r) {
+		t.Fatalf("expected a pipe to count as piped stdin")
+	}
+	if tty, err := os.Open(os.DevNull); err == nil {
+		defer tty.Close()
+		if stdinIsPiped(tty) {
+			t.Errorf("expected a character device not to count as piped stdin")
+		}
+	}
+
+	go func() {
+		w.WriteString("val x = 1 + 2\nif (x != 3) { x + nil }\n")
+		w.Close()
+	}()
+	source, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if err := flag.CommandLine.Parse([]string{}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	config := newConfiguration(t.TempDir(), stdinModule)
+	if code := run(stdinPath, source, config); code != 0 {
+		t.Fatalf("expected piped program to succeed, got exit code %d", code)
+	}
+	if code := run(stdinPath, []byte("val x = 1 + 2\nif (x == 3) { x + nil }\n"), config); code != 1 {
+		t.Fatalf("expected a throwing piped program to fail, got exit code %d", code)
+	}
+}
+
+func TestCheckParsesWithoutRunning(t *testing.T) {
+	var out bytes.Buffer
+	if code := check("ok.slug", []byte("val f = fn() { g() }\nval g = fn() { 1 }\nf()"), newConfiguration(".", "ok"), &out); code != 0 {
+		t.Fatalf("expected a valid program to pass, got exit code %d: %s", code, out.String())
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output for a valid program, got %q", out.String())
+	}
+
+	if code := check("bad.slug", []byte("var x = (1 + \n"), newConfiguration(".", "bad"), &out); code != 1 {
+		t.Fatalf("expected a syntax error to fail, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "Parse errors:") {
+		t.Errorf("expected parse errors to be reported, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := check("recur.slug", []byte("var f = fn(n) { 1 + recur(n) }"), newConfiguration(".", "recur"), &out); code != 1 {
+		t.Fatalf("expected recur outside tail position to fail, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "tail position") {
+		t.Errorf("expected the recur error to be reported, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := check("undefined.slug", []byte("val x = 1\nprintln(x + y)"), newConfiguration(".", "undefined"), &out); code != 1 {
+		t.Fatalf("expected an undefined name to fail, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "identifier not found: y") {
+		t.Errorf("expected the undefined name to be reported, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := check("unused.slug", []byte("val x = 1\nval y = 2\nprintln(y)"), newConfiguration(".", "unused"), &out); code != 0 {
+		t.Fatalf("expected an unused binding to only warn, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "Warning: unused binding: x") {
+		t.Errorf("expected the unused binding to be reported, got %q", out.String())
+	}
+
+	out.Reset()
+	if code := check("dead.slug", []byte("val f = fn() {\n  return 1\n  println(2)\n}\nf()"), newConfiguration(".", "dead"), &out); code != 0 {
+		t.Fatalf("expected unreachable code to only warn, got exit code %d", code)
+	}
+	if !strings.Contains(out.String(), "Warning: unreachable code after return") {
+		t.Errorf("expected the unreachable code to be reported, got %q", out.String())
+	}
+}
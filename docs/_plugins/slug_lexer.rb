@@ -38,7 +38,7 @@ module Rouge
         rule %r/\b(nursery|limit|spawn|await|within)\b/, Keyword
 
         rule %r{/>|=>|\.\.\.|\?\?\?|:\+|\+:}, Operator
-        rule %r/[=!<>]=?|&&|\|\||<<|>>|[+\-*\/%~^&|]/, Operator
+        rule %r/[=!<>]=?|&&|\|\||\?\?|<<|>>|[+\-*\/%~^&|]/, Operator
         rule %r/[(){}\[\],.;:]/, Punctuation
 
         rule %r/[A-Za-z_][A-Za-z0-9_]*/, Name
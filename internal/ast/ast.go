@@ -220,6 +220,12 @@ func (bs *BlockStatement) String() string {
 }
 
 // Expressions
+
+// Spanned is implemented by nodes that record the source range they cover,
+// so diagnostics can underline the whole node rather than a single column.
+type Spanned interface {
+	Span() (start, end int) // byte offsets, end is exclusive
+}
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value string
@@ -228,6 +234,7 @@ type Identifier struct {
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) Span() (int, int)     { return i.Token.Position, i.Token.End }
 
 type SymbolLiteral struct {
 	Token token.Token
@@ -288,10 +295,13 @@ type InfixExpression struct {
 	Left     Expression
 	Operator string
 	Right    Expression
+	Start    int // src index of the left operand
+	End      int // src index just past the right operand
 }
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Span() (int, int)     { return ie.Start, ie.End }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 
@@ -304,6 +314,34 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// ComparisonChain is `a < b <= c`, which holds when every adjacent pair of
+// operands compares true. Each operand is evaluated at most once.
+type ComparisonChain struct {
+	Token     token.Token // The first comparison operator
+	Operands  []Expression
+	Operators []string // Operators[i] sits between Operands[i] and Operands[i+1]
+	Start     int      // src index of the first operand
+	End       int      // src index just past the last operand
+}
+
+func (cc *ComparisonChain) expressionNode()      {}
+func (cc *ComparisonChain) TokenLiteral() string { return cc.Token.Literal }
+func (cc *ComparisonChain) Span() (int, int)     { return cc.Start, cc.End }
+func (cc *ComparisonChain) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	for i, operand := range cc.Operands {
+		if i > 0 {
+			out.WriteString(" " + cc.Operators[i-1] + " ")
+		}
+		out.WriteString(operand.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type IfExpression struct {
 	Token      token.Token // The 'if' token
 	Condition  Expression
@@ -409,10 +447,13 @@ type CallExpression struct {
 	Function   Expression  // Identifier or FunctionLiteral
 	Arguments  []Expression
 	IsTailCall bool // Whether this is a tail call
+	Start      int  // src index of the callee
+	End        int  // src index just past the closing ')'
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Span() (int, int)     { return ce.Start, ce.End }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -517,9 +558,10 @@ func (al *ListLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token // The [ token
-	Left  Expression
-	Index Expression
+	Token    token.Token // The [ token
+	Left     Expression
+	Index    Expression
+	Optional bool // true for `a?.b`, which yields nil when Left is nil
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -529,6 +571,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -564,6 +609,7 @@ func (se *SliceExpression) String() string {
 type MapLiteral struct {
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
+	Keys  []Expression // keys of Pairs in source order, and *SpreadExpression entries which have no pair
 }
 
 func (hl *MapLiteral) expressionNode()      {}
@@ -572,8 +618,12 @@ func (hl *MapLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys {
+		if _, ok := key.(*SpreadExpression); ok {
+			pairs = append(pairs, key.String())
+			continue
+		}
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -586,14 +636,14 @@ func (hl *MapLiteral) String() string {
 type StructField struct {
 	Token   token.Token
 	Name    string
-	Hint    string
+	Tags    []string // type hint and field tags such as @nonnil, in source order
 	Default Expression
 }
 
 func (sf *StructField) String() string {
 	var out bytes.Buffer
-	if sf.Hint != "" {
-		out.WriteString(sf.Hint)
+	for _, tag := range sf.Tags {
+		out.WriteString(tag)
 		out.WriteString(" ")
 	}
 	out.WriteString(sf.Name)
@@ -640,8 +690,8 @@ func (sf *StructInitField) String() string {
 }
 
 type StructInitExpression struct {
-	Token  token.Token // the '{' token
-	Schema Expression
+	Token  token.Token // the '{' token, or '#{' for an anonymous struct
+	Schema Expression  // nil for an anonymous struct literal
 	Fields []*StructInitField
 }
 
@@ -649,8 +699,12 @@ func (si *StructInitExpression) expressionNode()      {}
 func (si *StructInitExpression) TokenLiteral() string { return si.Token.Literal }
 func (si *StructInitExpression) String() string {
 	var out bytes.Buffer
-	out.WriteString(si.Schema.String())
-	out.WriteString(" {")
+	if si.Schema == nil {
+		out.WriteString("#{")
+	} else {
+		out.WriteString(si.Schema.String())
+		out.WriteString(" {")
+	}
 	parts := []string{}
 	for _, f := range si.Fields {
 		parts = append(parts, f.String())
@@ -882,6 +936,33 @@ func (lp *LiteralPattern) patternNode()         {}
 func (lp *LiteralPattern) TokenLiteral() string { return lp.Token.Literal }
 func (lp *LiteralPattern) String() string       { return lp.Value.String() }
 
+// StringConcatPattern matches a string by a literal prefix and/or suffix and
+// binds what is left in between.
+// Syntax: "prefix" + rest, rest + "suffix" or "prefix" + rest + "suffix"
+type StringConcatPattern struct {
+	Token  token.Token    // the first token of the pattern
+	Prefix *StringLiteral // nil when there is no prefix
+	Rest   MatchPattern   // IdentifierPattern or WildcardPattern
+	Suffix *StringLiteral // nil when there is no suffix
+}
+
+func (sp *StringConcatPattern) expressionNode()      {}
+func (sp *StringConcatPattern) patternNode()         {}
+func (sp *StringConcatPattern) TokenLiteral() string { return sp.Token.Literal }
+func (sp *StringConcatPattern) String() string {
+	var out bytes.Buffer
+	if sp.Prefix != nil {
+		out.WriteString(sp.Prefix.String())
+		out.WriteString(" + ")
+	}
+	out.WriteString(sp.Rest.String())
+	if sp.Suffix != nil {
+		out.WriteString(" + ")
+		out.WriteString(sp.Suffix.String())
+	}
+	return out.String()
+}
+
 // IdentifierPattern for binding values to variables
 type IdentifierPattern struct {
 	Token token.Token
@@ -1021,6 +1102,7 @@ type StructPattern struct {
 	Token  token.Token // The schema identifier token
 	Schema *Identifier
 	Fields []*StructPatternField
+	Spread MatchPattern // Optional binding for the fields not named in the pattern, as a map
 }
 
 func (sp *StructPattern) expressionNode()      {}
@@ -1034,6 +1116,9 @@ func (sp *StructPattern) String() string {
 	for _, f := range sp.Fields {
 		parts = append(parts, f.Name+": "+f.Pattern.String())
 	}
+	if sp.Spread != nil {
+		parts = append(parts, sp.Spread.String())
+	}
 	out.WriteString(strings.Join(parts, ", "))
 	out.WriteString("}")
 	return out.String()
@@ -7,11 +7,13 @@ package dec64
 // - Not thoroughly tested for all edge cases
 // - May have rounding errors in certain operations
 // - No guarantees of numerical stability
+// - Results beyond the exponent range are NaN (too large) or zero (too small), never wrapped
 
 import (
 	"errors"
 	"fmt"
 	"math"
+	"math/bits"
 	"strconv"
 	"strings"
 )
@@ -68,7 +70,7 @@ func FromInt64(coef int64) Dec64 {
 		exp++
 	}
 
-	return normalizeTowardZero(coef, exp)
+	return normalizeTowardZero(coef, int(exp))
 }
 
 // FromFloat64 converts a float64 to Dec64.
@@ -123,7 +125,7 @@ func FromFloat64(f float64) Dec64 {
 		return NAN
 	}
 
-	return normalizeTowardZero(coefInt, exp)
+	return normalizeTowardZero(coefInt, int(exp))
 }
 
 func FromString(s string) (Dec64, error) {
@@ -177,7 +179,7 @@ func FromString(s string) (Dec64, error) {
 		coef = -coef
 	}
 
-	return New(coef, -int8(fracLen)+int8(exp)).Normalize(), nil
+	return normalizeTowardZero(coef, int(exp)-fracLen), nil
 }
 
 // Coefficient extracts the integer part
@@ -225,7 +227,12 @@ func (a Dec64) ToFloat64() float64 {
 	return float64(a.Coefficient()) * math.Pow10(int(a.Exponent()))
 }
 
+// Add returns a + b. Digits of the smaller operand that fall below the result's precision are
+// truncated toward zero; a sum too large for the exponent range is NaN.
 func (a Dec64) Add(b Dec64) Dec64 {
+	if a.IsNaN() || b.IsNaN() {
+		return NAN
+	}
 	ca, cb, e := normalizePair(a, b)
 	return normalizeTowardZero(ca+cb, e)
 }
@@ -238,7 +245,12 @@ func (a Dec64) Neg() Dec64 {
 	return New(-a.Coefficient(), a.Exponent())
 }
 
+// Mul returns a * b, truncating the coefficient toward zero to fit. A product too large for the
+// exponent range is NaN and one too small for it is zero.
 func (a Dec64) Mul(b Dec64) Dec64 {
+	if a.IsNaN() || b.IsNaN() {
+		return NAN
+	}
 	ca, cb := a.Coefficient(), b.Coefficient()
 	ea, eb := a.Exponent(), b.Exponent()
 
@@ -252,27 +264,22 @@ func (a Dec64) Mul(b Dec64) Dec64 {
 		sign = -1
 	}
 
-	ua := abs64(ca)
-	ub := abs64(cb)
-
-	exp := int(ea + eb)
-
-	const maxInt64 = int64(^uint64(0) >> 1)
+	exp := int(ea) + int(eb)
 
-	// Scale down to prevent overflow
-	for ua > maxInt64/ub {
-		// Drop one decimal digit from the larger side
-		if ua >= ub {
-			ua /= 10
-		} else {
-			ub /= 10
-		}
+	// Multiply into 128 bits, then drop digits until the product fits a coefficient again
+	hi, lo := bits.Mul64(uint64(abs64(ca)), uint64(abs64(cb)))
+	for hi != 0 {
+		var rem uint64
+		hi, rem = hi/10, hi%10
+		lo, _ = bits.Div64(rem, lo, 10)
+		exp++
+	}
+	for lo > uint64(MAX_COEFF) {
+		lo /= 10
 		exp++
 	}
 
-	coef := sign * (ua * ub)
-
-	return normalizeTowardZero(coef, int8(exp))
+	return normalizeTowardZero(sign*int64(lo), exp)
 }
 
 func (a Dec64) Div(b Dec64, precision int, rounding RoundingMode) Dec64 {
@@ -321,7 +328,7 @@ func (a Dec64) Div(b Dec64, precision int, rounding RoundingMode) Dec64 {
 		}
 	}
 
-	return normalizeTowardZero(quotient, ea-eb-int8(usedPrecision))
+	return normalizeTowardZero(quotient, int(ea)-int(eb)-usedPrecision)
 }
 
 // scaleForDiv chooses the largest p <= requestedP such that:
@@ -375,12 +382,20 @@ func scaleForDiv(ca, cb int64, requestedP int) (scaled int64, usedP int) {
 	return cur, p
 }
 
+// Mod returns the truncated remainder of a / b, which takes the sign of a.
 func (a Dec64) Mod(b Dec64) Dec64 {
 	if b.IsZero() {
 		return NAN
 	}
+	if a.Abs().Lt(b.Abs()) {
+		return a
+	}
 
 	ca, cb, e := normalizePair(a, b)
+	if cb == 0 {
+		// b is below a's precision, so at this precision a is an exact multiple of it
+		return ZERO
+	}
 	return normalizeTowardZero(ca%cb, e)
 }
 
@@ -427,6 +442,31 @@ func (a Dec64) Abs() Dec64 {
 	return New(coef, a.Exponent())
 }
 
+// Round rounds half away from zero to at most `places` digits after the decimal point.
+func (a Dec64) Round(places int) Dec64 {
+	exp := int(a.Exponent())
+	if a.IsNaN() || exp >= -places {
+		return a
+	}
+
+	shift := -places - exp
+	if shift > 17 {
+		// every coefficient is below half of 10^18
+		return ZERO
+	}
+	p := pow10(int64(shift))
+	coef := a.Coefficient()
+	q, r := coef/p, coef%p
+	if abs64(r)*2 >= p {
+		if coef > 0 {
+			q++
+		} else {
+			q--
+		}
+	}
+	return normalizeTowardZero(q, -places)
+}
+
 func (a Dec64) IsZero() bool {
 	return a.Coefficient() == 0 && !a.IsNaN()
 }
@@ -436,7 +476,7 @@ func (a Dec64) IsNaN() bool {
 }
 
 func (a Dec64) Normalize() Dec64 {
-	return normalizeTowardZero(a.Coefficient(), a.Exponent())
+	return normalizeTowardZero(a.Coefficient(), int(a.Exponent()))
 }
 
 func (a Dec64) String() string {
@@ -448,17 +488,27 @@ func (a Dec64) String() string {
 
 	neg := a.Coefficient() < 0
 	mag := abs64(a.Coefficient())
+	exp := a.Exponent()
+
+	// Drop trailing fractional zeros so equal values print the same way
+	for exp < 0 && mag%10 == 0 {
+		mag /= 10
+		exp++
+	}
 
 	// Convert to digits
 	digits := []byte(strconv.FormatInt(mag, 10))
-	exp := a.Exponent()
 
 	var result string
 	switch {
 	case exp >= 16 || (exp < 0 && -exp >= int8(len(digits))+16):
 		// Use scientific notation for large exponents
-		point := 1
-		result = string(digits[:point]) + "." + string(digits[point:]) + "e" + strconv.FormatInt(int64(exp)+int64(len(digits))-1, 10)
+		mantissa := strings.TrimRight(string(digits[1:]), "0")
+		result = string(digits[:1])
+		if mantissa != "" {
+			result += "." + mantissa
+		}
+		result += "e" + strconv.FormatInt(int64(exp)+int64(len(digits))-1, 10)
 	case exp >= 0:
 		// Append zeroes
 		result = string(digits) + strings.Repeat("0", int(exp))
@@ -521,7 +571,10 @@ func Min(values ...Dec64) Dec64 {
 	return min
 }
 
-func normalizeTowardZero(coef int64, exp int8) Dec64 {
+// normalizeTowardZero packs coef×10^exp into a Dec64. The exponent is taken as an int so callers
+// can hand over results outside the int8 range: values too small for it truncate to zero and
+// values too large for it are NaN rather than wrapping around.
+func normalizeTowardZero(coef int64, exp int) Dec64 {
 	if coef == 0 {
 		return ZERO
 	}
@@ -532,14 +585,29 @@ func normalizeTowardZero(coef int64, exp int8) Dec64 {
 		exp++
 	}
 
+	// -128 marks NaN, so -127 is the smallest usable exponent
+	for exp < -127 && coef != 0 {
+		coef /= 10
+		exp++
+	}
+	if coef == 0 {
+		return ZERO
+	}
+
+	// Borrow from the coefficient while it has room before giving up on a large exponent
+	for exp > 127 && abs64(coef) <= MAX_COEFF/10 {
+		coef *= 10
+		exp--
+	}
+
 	// If we still can't fit, give up.
-	if coef > MAX_COEFF || coef < MIN_COEFF {
+	if coef > MAX_COEFF || coef < MIN_COEFF || exp > 127 {
 		return NAN
 	}
 
 	const maxDigits = 16
 	if abs64(coef) >= pow10(maxDigits) {
-		for coef%10 == 0 {
+		for coef%10 == 0 && exp < 127 {
 			coef /= 10
 			exp++
 		}
@@ -557,29 +625,40 @@ func normalizeTowardZero(coef int64, exp int8) Dec64 {
 		}
 	}
 
-	return New(coef, exp)
+	return New(coef, int8(exp))
 }
 
-func normalizePair(a, b Dec64) (int64, int64, int8) {
-	ea, eb := a.Exponent(), b.Exponent()
+// normalizePair brings a and b to a shared exponent so their coefficients can be combined.
+func normalizePair(a, b Dec64) (int64, int64, int) {
+	ea, eb := int(a.Exponent()), int(b.Exponent())
 	ca, cb := a.Coefficient(), b.Coefficient()
 
 	if ea > eb {
-		shift := int(ea - eb)
-		for i := 0; i < shift; i++ {
-			ca *= 10
-		}
-		return ca, cb, eb
+		ca, cb, e := alignExponents(ca, ea, cb, eb)
+		return ca, cb, e
 	} else if eb > ea {
-		shift := int(eb - ea)
-		for i := 0; i < shift; i++ {
-			cb *= 10
-		}
-		return ca, cb, ea
+		cb, ca, e := alignExponents(cb, eb, ca, ea)
+		return ca, cb, e
 	}
 	return ca, cb, ea
 }
 
+// alignExponents scales hi (the operand with the larger exponent) up while the sum of the two
+// coefficients still fits an int64, then truncates lo toward zero for whatever difference is left.
+func alignExponents(hi int64, hiExp int, lo int64, loExp int) (int64, int64, int) {
+	const headroom = int64(^uint64(0)>>1) / 20
+
+	for hiExp > loExp && abs64(hi) <= headroom {
+		hi *= 10
+		hiExp--
+	}
+	for loExp < hiExp {
+		lo /= 10
+		loExp++
+	}
+	return hi, lo, hiExp
+}
+
 func abs64(v int64) int64 {
 	if v < 0 {
 		return -v
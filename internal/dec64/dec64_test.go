@@ -96,6 +96,56 @@ func TestDiv(t *testing.T) {
 	}
 }
 
+func TestMod(t *testing.T) {
+	cases := []struct {
+		name     string
+		a, b     Dec64
+		expected Dec64
+	}{
+		{"7 % 3", New(7, 0), New(3, 0), New(1, 0)},
+		{"-7 % 3", New(-7, 0), New(3, 0), New(-1, 0)},
+		{"7 % -3", New(7, 0), New(-3, 0), New(1, 0)},
+		{"-7 % -3", New(-7, 0), New(-3, 0), New(-1, 0)},
+		{"-7.5 % 2", New(-75, -1), New(2, 0), New(-15, -1)},
+		{"-2 % 3", New(-2, 0), New(3, 0), New(-2, 0)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := c.a.Mod(c.b)
+			if !result.Eq(c.expected) {
+				t.Errorf("expected %s, got %s", c.expected.String(), result.String())
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        Dec64
+		places   int
+		expected string
+	}{
+		{"Rounds down", New(123444, -5), 2, "1.23"},
+		{"Rounds half up", New(12345, -4), 3, "1.235"},
+		{"Rounds negative away from zero", New(-12345, -4), 3, "-1.235"},
+		{"Carries into the integer part", New(9999, -3), 2, "10"},
+		{"Leaves shorter values alone", New(15, -1), 4, "1.5"},
+		{"Rounds to an integer", New(25, -1), 0, "3"},
+		{"Tiny values round to zero", New(1, -30), 4, "0"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := c.a.Round(c.places)
+			if result.String() != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, result.String())
+			}
+		})
+	}
+}
+
 func TestStringFormatParse(t *testing.T) {
 	cases := []string{
 		"0", "1", "-1", "123.456", "-0.001", "-9.9e-9", "42.0", "1e3",
@@ -188,6 +238,14 @@ func TestStringFormat_EdgeCases(t *testing.T) {
 		{New(-12345, -3), "-12.345"},
 		{New(1, 10), "10000000000"},
 		{New(-1, -10), "-0.0000000001"},
+		{New(10, -1), "1"},
+		{New(150, -2), "1.5"},
+		{New(100, -3), "0.1"},
+		{New(-2500, -3), "-2.5"},
+		{New(1200, 0), "1200"},
+		{New(1, 20), "1e20"},
+		{New(1230, 20), "1.23e23"},
+		{New(-15, -31), "-1.5e-30"},
 	}
 
 	for _, c := range cases {
@@ -490,3 +548,39 @@ func TestMin(t *testing.T) {
 		t.Errorf("Min(NAN, ONE, ZERO) expected: NAN, got: %v", result)
 	}
 }
+
+func TestOverflowPolicy(t *testing.T) {
+	cases := []struct {
+		name     string
+		result   Dec64
+		expected string
+	}{
+		{"max coefficient squared keeps 17 digits", New(MAX_COEFF, 0).Mul(New(MAX_COEFF, 0)), "1.2980742146337068e33"},
+		{"16 nines squared", New(9999999999999999, 0).Mul(New(9999999999999999, 0)), "9.999999999999998e31"},
+		{"product near the exponent limit", New(1, 120).Mul(New(1, 7)), "1e127"},
+		{"product past the exponent limit", New(1, 120).Mul(New(1, 120)), "NaN"},
+		{"negative product past the exponent limit", New(-1, 120).Mul(New(1, 120)), "NaN"},
+		{"product below the exponent limit", New(1, -120).Mul(New(1, -120)), "0"},
+		{"max coefficient doubled", New(MAX_COEFF, 0).Add(New(MAX_COEFF, 0)), "72057594037927930"},
+		{"sum across distant exponents", New(1, 100).Add(ONE), "1e100"},
+		{"small plus large", ONE.Add(New(1, 30)), "1e30"},
+		{"sum past the exponent limit", New(MAX_COEFF, 127).Add(New(MAX_COEFF, 127)), "NaN"},
+		{"NaN propagates through add", NAN.Add(ONE), "NaN"},
+		{"NaN propagates through mul", ONE.Mul(NAN), "NaN"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.result.String(); got != c.expected {
+				t.Errorf("expected %s, got %s / %s", c.expected, got, c.result.StringRaw())
+			}
+		})
+	}
+
+	if New(1, 30).Cmp(ONE) != 1 || ONE.Cmp(New(1, 30)) != -1 {
+		t.Errorf("expected comparisons across distant exponents to order correctly")
+	}
+	if got := New(1, -30).Mod(New(1, 30)); got != New(1, -30) {
+		t.Errorf("expected a small dividend to be its own remainder, got %s", got.String())
+	}
+}
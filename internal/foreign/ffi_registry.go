@@ -17,6 +17,7 @@ func GetForeignFunctions() map[string]*object.Foreign {
 		"slug.crypto.md5":    fnCryptoMd5(),
 		"slug.crypto.sha256": fnCryptoSha256(),
 		"slug.crypto.sha512": fnCryptoSha512(),
+		"slug.crypto.uuid":   fnCryptoUuid(),
 
 		"slug.debug.ident": fnDebugIdent(),
 
@@ -51,12 +52,39 @@ func GetForeignFunctions() map[string]*object.Foreign {
 		"slug.io.tcp.write":   fnIoTcpWrite(),
 		"slug.io.tcp.close":   fnIoTcpClose(),
 
+		"slug.list.array":              fnListArray(),
+		"slug.list.getAt":              fnListGetAt(),
+		"slug.list.push":               fnListPush(),
+		"slug.list.set":                fnListSet(),
+		"slug.list.setAdd":             fnListSetAdd(),
+		"slug.list.setAt":              fnListSetAt(),
+		"slug.list.setDiff":            fnListSetDiff(),
+		"slug.list.setHas":             fnListSetHas(),
+		"slug.list.setIntersect":       fnListSetIntersect(),
+		"slug.list.setUnion":           fnListSetUnion(),
 		"slug.list.sortWithComparator": fnListSortWithComparator(),
+		"slug.list.toList":             fnListToList(),
+		"slug.list.unique":             fnListUnique(),
+
+		"slug.map.dict":       fnMapDict(),
+		"slug.map.dictDelete": fnMapDictDelete(),
+		"slug.map.dictGet":    fnMapDictGet(),
+		"slug.map.dictKeys":   fnMapDictKeys(),
+		"slug.map.dictSet":    fnMapDictSet(),
+		"slug.map.toMap":      fnMapToMap(),
 
 		"slug.math.ceil":     fnMathCeil(),
 		"slug.math.floor":    fnMathFloor(),
 		"slug.math.rndRange": fnMathRndRange(),
+		"slug.math.sum":      fnMathSum(),
+		"slug.math.product":  fnMathProduct(),
 		"slug.math.sqrt":     fnMathSqrt(),
+		"slug.math.sin":      fnMathSin(),
+		"slug.math.cos":      fnMathCos(),
+		"slug.math.tan":      fnMathTan(),
+		"slug.math.ln":       fnMathLn(),
+		"slug.math.log10":    fnMathLog10(),
+		"slug.math.exp":      fnMathExp(),
 
 		"slug.meta.hasTag":           fnMetaHasTag(),
 		"slug.meta.getTag":           fnMetaGetTag(),
@@ -65,6 +93,9 @@ func GetForeignFunctions() map[string]*object.Foreign {
 		"slug.meta.searchModuleTags": fnMetaSearchModuleTags(),
 		"slug.meta.searchScopeTags":  fnMetaSearchScopeTags(),
 
+		"slug.regex.capture":       fnRegexCapture(),
+		"slug.regex.captureNamed":  fnRegexCaptureNamed(),
+		"slug.regex.find":          fnRegexFind(),
 		"slug.regex.findAll":       fnRegexFindAll(),
 		"slug.regex.findAllGroups": fnRegexFindAllGroups(),
 		"slug.regex.indexOf":       fnRegexIndexOf(),
@@ -74,22 +105,44 @@ func GetForeignFunctions() map[string]*object.Foreign {
 
 		"slug.std.type":        fnStdType(),
 		"slug.std.isDefined":   fnStdIsDefined(),
+		"slug.std.clone":       fnStdClone(),
 		"slug.std.fmt":         fnStdFmt(),
+		"slug.std.frozen":      fnStdFrozen(),
 		"slug.std.update":      fnStdUpdate(),
 		"slug.std.swap":        fnStdSwap(),
 		"slug.std.parseNumber": fnStdParseNumber(),
 		"slug.std.get":         fnStdGet(),
+		"slug.std.all":         fnStdAll(),
+		"slug.std.any":         fnStdAny(),
+		"slug.std.dispatch":    fnStdDispatch(),
+		"slug.std.find":        fnStdFind(),
+		"slug.std.findIndex":   fnStdFindIndex(),
+		"slug.std.foreach":     fnStdForeach(),
+		"slug.std.isa":         fnStdIsa(),
 		"slug.std.keys":        fnStdKeys(),
+		"slug.std.memoize":     fnStdMemoize(),
+		"slug.std.once":        fnStdOnce(),
+		"slug.std.retry":       fnStdRetry(),
+		"slug.std.values":      fnStdValues(),
+		"slug.std.entries":     fnStdEntries(),
 		"slug.std.sym":         fnStdSym(),
+		"slug.std.hashKey":     fnStdHashKey(),
 		"slug.std.label":       fnStdLabel(),
 		"slug.std.put":         fnStdPut(),
 		"slug.std.remove":      fnStdRemove(),
 
 		// string functions
-		"slug.string.indexOf": fnStringIndexOf(),
-		"slug.string.toLower": fnStringToLower(),
-		"slug.string.toUpper": fnStringToUpper(),
-		"slug.string.trim":    fnStringTrim(),
+		"slug.string.append":    fnStringAppend(),
+		"slug.string.build":     fnStringBuild(),
+		"slug.string.builder":   fnStringBuilder(),
+		"slug.string.indexOf":   fnStringIndexOf(),
+		"slug.string.padLeft":   fnStringPadLeft(),
+		"slug.string.padRight":  fnStringPadRight(),
+		"slug.string.toLower":   fnStringToLower(),
+		"slug.string.toUpper":   fnStringToUpper(),
+		"slug.string.trim":      fnStringTrim(),
+		"slug.string.trimLeft":  fnStringTrimLeft(),
+		"slug.string.trimRight": fnStringTrimRight(),
 
 		"slug.sys.exit":      fnSysExit(),
 		"slug.sys.setEnv":    fnSysSetEnv(),
@@ -103,5 +156,7 @@ func GetForeignFunctions() map[string]*object.Foreign {
 		"slug.time.fmtClock":   fnTimeFmtClock(),
 		"slug.time.clockNanos": fnTimeClockNanos(),
 		"slug.time.sleep":      fnTimeSleep(),
+		"slug.time.parseTime":  fnTimeParseTime(),
+		"slug.time.formatTime": fnTimeFormatTime(),
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"crypto/sha256"
 	"crypto/sha512"
+	"fmt"
 	"slug/internal/object"
 )
 
@@ -66,3 +67,22 @@ func fnCryptoSha512() *object.Foreign {
 		},
 	}
 }
+
+func fnCryptoUuid() *object.Foreign {
+	return &object.Foreign{
+		Name: "uuid",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+
+			// RFC 4122 version 4: random bits with the version and variant fields set
+			var b [16]byte
+			ctx.RandomBytes(b[:])
+			b[6] = (b[6] & 0x0f) | 0x40
+			b[8] = (b[8] & 0x3f) | 0x80
+
+			return &object.String{Value: fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])}
+		},
+	}
+}
@@ -65,3 +65,280 @@ func fnListSortWithComparator() *object.Foreign {
 		},
 	}
 }
+
+func fnListUnique() *object.Foreign {
+	return &object.Foreign{
+		Name: "unique",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			listObj, ok := args[0].(*object.List)
+			if !ok {
+				return ctx.NewError("argument to `unique` must be a LIST, got=%s", args[0].Type())
+			}
+
+			// Hashable elements are bucketed by MapKey; keys can collide (numbers hash by their
+			// integer part) so candidates in a bucket are still compared. Other elements fall back
+			// to a linear scan.
+			buckets := make(map[object.MapKey][]object.Object)
+			var unhashed []object.Object
+			result := make([]object.Object, 0, len(listObj.Elements))
+
+			contains := func(seen []object.Object, el object.Object) bool {
+				for _, s := range seen {
+					if s == el || ctx.ObjectsEqual(s, el) {
+						return true
+					}
+				}
+				return false
+			}
+
+			for _, el := range listObj.Elements {
+				if h, ok := el.(object.Hashable); ok {
+					key := h.MapKey()
+					if contains(buckets[key], el) {
+						continue
+					}
+					buckets[key] = append(buckets[key], el)
+				} else {
+					if contains(unhashed, el) {
+						continue
+					}
+					unhashed = append(unhashed, el)
+				}
+				result = append(result, el)
+			}
+
+			return &object.List{Elements: result}
+		},
+	}
+}
+
+func fnListArray() *object.Foreign {
+	return &object.Foreign{
+		Name: "array",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			arr := &object.Array{}
+			if len(args) == 1 && args[0] != ctx.Nil() {
+				list, ok := args[0].(*object.List)
+				if !ok {
+					return ctx.NewError("argument to `array` must be a LIST, got=%s", args[0].Type())
+				}
+				arr.Elements = append(arr.Elements, list.Elements...)
+			}
+			return arr
+		},
+	}
+}
+
+func arrayArgument(ctx object.EvaluatorContext, fnName string, arg object.Object) (*object.Array, object.Object) {
+	arr, ok := arg.(*object.Array)
+	if !ok {
+		return nil, ctx.NewError("first argument to `%s` must be an ARRAY, got=%s", fnName, arg.Type())
+	}
+	return arr, nil
+}
+
+func arrayIndexArgument(ctx object.EvaluatorContext, fnName string, arr *object.Array, arg object.Object) (int, object.Object) {
+	num, ok := arg.(*object.Number)
+	if !ok {
+		return 0, ctx.NewError("index argument to `%s` must be a NUMBER, got=%s", fnName, arg.Type())
+	}
+	idx, ok := arr.Index(num.Value.ToInt())
+	if !ok {
+		return 0, ctx.NewError("index %s out of range for array of length %d", num.Inspect(), len(arr.Elements))
+	}
+	return idx, nil
+}
+
+func fnListPush() *object.Foreign {
+	return &object.Foreign{
+		Name: "push",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			arr, errObj := arrayArgument(ctx, "push", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			arr.Elements = append(arr.Elements, args[1])
+			return arr
+		},
+	}
+}
+
+func fnListGetAt() *object.Foreign {
+	return &object.Foreign{
+		Name: "getAt",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			arr, errObj := arrayArgument(ctx, "getAt", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			idx, errObj := arrayIndexArgument(ctx, "getAt", arr, args[1])
+			if errObj != nil {
+				return errObj
+			}
+			return arr.Elements[idx]
+		},
+	}
+}
+
+func fnListSetAt() *object.Foreign {
+	return &object.Foreign{
+		Name: "setAt",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			arr, errObj := arrayArgument(ctx, "setAt", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			idx, errObj := arrayIndexArgument(ctx, "setAt", arr, args[1])
+			if errObj != nil {
+				return errObj
+			}
+			arr.Elements[idx] = args[2]
+			return arr
+		},
+	}
+}
+
+func fnListToList() *object.Foreign {
+	return &object.Foreign{
+		Name: "toList",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if set, ok := args[0].(*object.Set); ok {
+				return &object.List{Elements: set.Elements()}
+			}
+			arr, errObj := arrayArgument(ctx, "toList", args[0])
+			if errObj != nil {
+				return errObj
+			}
+			return arr.ToList()
+		},
+	}
+}
+
+func fnListSet() *object.Foreign {
+	return &object.Foreign{
+		Name: "set",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			set := object.NewSet()
+			if len(args) == 1 && args[0] != ctx.Nil() {
+				list, ok := args[0].(*object.List)
+				if !ok {
+					return ctx.NewError("argument to `set` must be a LIST, got=%s", args[0].Type())
+				}
+				values := make([]object.Hashable, 0, len(list.Elements))
+				for _, el := range list.Elements {
+					v, ok := object.HashableKey(el)
+					if !ok {
+						return ctx.NewError("unusable as set member: %s", el.Type())
+					}
+					values = append(values, v)
+				}
+				set = object.NewSet(values...)
+			}
+			return set
+		},
+	}
+}
+
+func setArgument(ctx object.EvaluatorContext, fnName string, arg object.Object) (*object.Set, object.Object) {
+	set, ok := arg.(*object.Set)
+	if !ok {
+		return nil, ctx.NewError("argument to `%s` must be a SET, got=%s", fnName, arg.Type())
+	}
+	return set, nil
+}
+
+func setMemberArgument(ctx object.EvaluatorContext, fnName string, args []object.Object) (*object.Set, object.Hashable, object.Object) {
+	if len(args) != 2 {
+		return nil, nil, ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	set, errObj := setArgument(ctx, fnName, args[0])
+	if errObj != nil {
+		return nil, nil, errObj
+	}
+	v, ok := object.HashableKey(args[1])
+	if !ok {
+		return nil, nil, ctx.NewError("unusable as set member: %s", args[1].Type())
+	}
+	return set, v, nil
+}
+
+func fnListSetAdd() *object.Foreign {
+	return &object.Foreign{
+		Name: "setAdd",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			set, v, errObj := setMemberArgument(ctx, "setAdd", args)
+			if errObj != nil {
+				return errObj
+			}
+			return set.With(v)
+		},
+	}
+}
+
+func fnListSetHas() *object.Foreign {
+	return &object.Foreign{
+		Name: "setHas",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			set, v, errObj := setMemberArgument(ctx, "setHas", args)
+			if errObj != nil {
+				return errObj
+			}
+			return ctx.NativeBoolToBooleanObject(set.Has(v))
+		},
+	}
+}
+
+// setOperation builds a foreign combining two sets with op.
+func setOperation(name string, op func(a, b *object.Set) *object.Set) *object.Foreign {
+	return &object.Foreign{
+		Name: name,
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			a, errObj := setArgument(ctx, name, args[0])
+			if errObj != nil {
+				return errObj
+			}
+			b, errObj := setArgument(ctx, name, args[1])
+			if errObj != nil {
+				return errObj
+			}
+			return op(a, b)
+		},
+	}
+}
+
+func fnListSetUnion() *object.Foreign {
+	return setOperation("setUnion", (*object.Set).Union)
+}
+
+func fnListSetIntersect() *object.Foreign {
+	return setOperation("setIntersect", (*object.Set).Intersect)
+}
+
+func fnListSetDiff() *object.Foreign {
+	return setOperation("setDiff", (*object.Set).Diff)
+}
@@ -0,0 +1,127 @@
+package foreign
+
+import (
+	"slug/internal/object"
+)
+
+func fnMapDict() *object.Foreign {
+	return &object.Foreign{
+		Name: "dict",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			d := &object.Dict{}
+			if len(args) == 1 && args[0] != ctx.Nil() {
+				m, ok := args[0].(*object.Map)
+				if !ok {
+					return ctx.NewError("argument to `dict` must be a MAP, got=%s", args[0].Type())
+				}
+				for _, pair := range m.OrderedPairs() {
+					d.Set(pair.Key.(object.Hashable), pair.Value)
+				}
+			}
+			return d
+		},
+	}
+}
+
+func dictArguments(ctx object.EvaluatorContext, fnName string, args []object.Object) (*object.Dict, object.Hashable, object.Object) {
+	d, ok := args[0].(*object.Dict)
+	if !ok {
+		return nil, nil, ctx.NewError("first argument to `%s` must be a DICT, got=%s", fnName, args[0].Type())
+	}
+	if len(args) < 2 {
+		return d, nil, nil
+	}
+	key, ok := object.HashableKey(args[1])
+	if !ok {
+		return nil, nil, ctx.NewError("unusable as map key: %s", args[1].Type())
+	}
+	return d, key, nil
+}
+
+func fnMapDictSet() *object.Foreign {
+	return &object.Foreign{
+		Name: "dictSet",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			d, key, errObj := dictArguments(ctx, "dictSet", args)
+			if errObj != nil {
+				return errObj
+			}
+			d.Set(key, args[2])
+			return d
+		},
+	}
+}
+
+func fnMapDictGet() *object.Foreign {
+	return &object.Foreign{
+		Name: "dictGet",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			d, key, errObj := dictArguments(ctx, "dictGet", args)
+			if errObj != nil {
+				return errObj
+			}
+			if v, ok := d.Get(key); ok {
+				return v
+			}
+			return ctx.Nil()
+		},
+	}
+}
+
+func fnMapDictDelete() *object.Foreign {
+	return &object.Foreign{
+		Name: "dictDelete",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			d, key, errObj := dictArguments(ctx, "dictDelete", args)
+			if errObj != nil {
+				return errObj
+			}
+			d.Delete(key)
+			return d
+		},
+	}
+}
+
+func fnMapDictKeys() *object.Foreign {
+	return &object.Foreign{
+		Name: "dictKeys",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			d, _, errObj := dictArguments(ctx, "dictKeys", args)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.List{Elements: d.Keys()}
+		},
+	}
+}
+
+func fnMapToMap() *object.Foreign {
+	return &object.Foreign{
+		Name: "toMap",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			d, _, errObj := dictArguments(ctx, "toMap", args)
+			if errObj != nil {
+				return errObj
+			}
+			return d.ToMap()
+		},
+	}
+}
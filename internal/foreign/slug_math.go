@@ -97,11 +97,15 @@ func fnMathCeil() *object.Foreign {
 	}
 }
 
-// sqrt returns the square root of n.
-// If n < 0, returns NaN.
-func fnMathSqrt() *object.Foreign {
+// floatPlaces rounds the float64 results below to the same 14 decimal places
+// as `/`, which also hides float noise such as sin(pi) not being exactly 0.
+const floatPlaces = 14
+
+// floatMathFn wraps a float64 function of one argument. Arguments outside the
+// domain, those for which inDomain returns false, are an error rather than NaN.
+func floatMathFn(name string, f func(float64) float64, inDomain func(float64) bool) *object.Foreign {
 	return &object.Foreign{
-		Name: "sqrt",
+		Name: name,
 		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
@@ -109,7 +113,7 @@ func fnMathSqrt() *object.Foreign {
 
 			nArg, ok := args[0].(*object.Number)
 			if !ok {
-				return ctx.NewError("argument 1 to `sqrt` must be a NUMBER, got=%s", args[0].Type())
+				return ctx.NewError("argument 1 to `%s` must be a NUMBER, got=%s", name, args[0].Type())
 			}
 
 			v := nArg.Value
@@ -117,21 +121,82 @@ func fnMathSqrt() *object.Foreign {
 				return &object.Number{Value: dec64.NAN}
 			}
 
-			// Negative check (dec64 has no Inf; treat negatives as invalid for sqrt)
-			if v.Coefficient() < 0 {
-				return &object.Number{Value: dec64.NAN}
+			x := v.ToFloat64()
+			if inDomain != nil && !inDomain(x) {
+				return ctx.NewError("`%s` is undefined for %s", name, v.String())
 			}
 
-			f := v.ToFloat64()
-			if f < 0 {
-				return &object.Number{Value: dec64.NAN}
+			return &object.Number{Value: dec64.FromFloat64(f(x)).Round(floatPlaces)}
+		},
+	}
+}
+
+func nonNegative(x float64) bool { return x >= 0 }
+
+func positive(x float64) bool { return x > 0 }
+
+func fnMathSqrt() *object.Foreign  { return floatMathFn("sqrt", math.Sqrt, nonNegative) }
+func fnMathSin() *object.Foreign   { return floatMathFn("sin", math.Sin, nil) }
+func fnMathCos() *object.Foreign   { return floatMathFn("cos", math.Cos, nil) }
+func fnMathTan() *object.Foreign   { return floatMathFn("tan", math.Tan, nil) }
+func fnMathLn() *object.Foreign    { return floatMathFn("ln", math.Log, positive) }
+func fnMathLog10() *object.Foreign { return floatMathFn("log10", math.Log10, positive) }
+func fnMathExp() *object.Foreign   { return floatMathFn("exp", math.Exp, nil) }
+
+func fnMathSum() *object.Foreign {
+	return &object.Foreign{
+		Name: "sum",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			nums, err := numberElements(ctx, "sum", args)
+			if err != nil {
+				return err
+			}
+			total := dec64.FromInt(0)
+			for _, n := range nums {
+				total = total.Add(n)
 			}
+			return &object.Number{Value: total}
+		},
+	}
+}
 
-			return &object.Number{Value: dec64.FromFloat64(math.Sqrt(f))}
+func fnMathProduct() *object.Foreign {
+	return &object.Foreign{
+		Name: "product",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			nums, err := numberElements(ctx, "product", args)
+			if err != nil {
+				return err
+			}
+			total := dec64.FromInt(1)
+			for _, n := range nums {
+				total = total.Mul(n)
+			}
+			return &object.Number{Value: total}
 		},
 	}
 }
 
+// numberElements unpacks the single list argument of an aggregate, which must hold only numbers.
+func numberElements(ctx object.EvaluatorContext, name string, args []object.Object) ([]dec64.Dec64, object.Object) {
+	if len(args) != 1 {
+		return nil, ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	list, ok := args[0].(*object.List)
+	if !ok {
+		return nil, ctx.NewError("argument to `%s` must be a LIST, got=%s", name, args[0].Type())
+	}
+	nums := make([]dec64.Dec64, len(list.Elements))
+	for i, el := range list.Elements {
+		n, ok := el.(*object.Number)
+		if !ok {
+			return nil, ctx.NewError("`%s` expects a list of numbers, element %d is %s", name, i, el.Type())
+		}
+		nums[i] = n.Value
+	}
+	return nums, nil
+}
+
 // random_range generates a random integer between min and max (inclusive).
 func fnMathRndRange() *object.Foreign {
 	return &object.Foreign{
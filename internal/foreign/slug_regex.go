@@ -25,12 +25,12 @@ func fnRegexMatches() *object.Foreign {
 				return ctx.NewError(err.Error())
 			}
 
-			matches, err := regexp.MatchString(pattern, str)
+			re, err := ctx.CompileRegex(pattern)
 			if err != nil {
 				return ctx.NewError(err.Error())
 			}
 
-			return ctx.NativeBoolToBooleanObject(matches)
+			return ctx.NativeBoolToBooleanObject(re.MatchString(str))
 		},
 	}
 }
@@ -54,7 +54,7 @@ func fnRegexIndexOf() *object.Foreign {
 				return ctx.NewError(err.Error())
 			}
 
-			re, err := regexp.Compile(pattern)
+			re, err := ctx.CompileRegex(pattern)
 			if err != nil {
 				return ctx.NewError(err.Error())
 			}
@@ -101,7 +101,7 @@ func fnRegexSplit() *object.Foreign {
 				return ctx.NewError(err.Error())
 			}
 
-			re, err := regexp.Compile(pattern)
+			re, err := ctx.CompileRegex(pattern)
 			if err != nil {
 				return ctx.NewError(err.Error())
 			}
@@ -118,6 +118,40 @@ func fnRegexSplit() *object.Foreign {
 	}
 }
 
+func fnRegexFind() *object.Foreign {
+	return &object.Foreign{
+		Name: "find",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			str, err := unpackString(args[0], "str")
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			pattern, err := unpackString(args[1], "pattern")
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			re, err := ctx.CompileRegex(pattern)
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			loc := re.FindStringIndex(str)
+			if loc == nil {
+				return ctx.Nil()
+			}
+
+			return &object.String{Value: str[loc[0]:loc[1]]}
+		},
+	}
+}
+
 func fnRegexFindAll() *object.Foreign {
 	return &object.Foreign{
 		Name: "findAll",
@@ -137,7 +171,7 @@ func fnRegexFindAll() *object.Foreign {
 				return ctx.NewError(err.Error())
 			}
 
-			re, err := regexp.Compile(pattern)
+			re, err := ctx.CompileRegex(pattern)
 			if err != nil {
 				return ctx.NewError(err.Error())
 			}
@@ -154,6 +188,97 @@ func fnRegexFindAll() *object.Foreign {
 	}
 }
 
+func fnRegexCapture() *object.Foreign {
+	return &object.Foreign{
+		Name: "capture",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			str, re, errObj := unpackRegexArgs(ctx, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			groups := captureGroups(re, str)
+			if groups == nil {
+				return ctx.Nil()
+			}
+
+			return &object.List{Elements: groups}
+		},
+	}
+}
+
+func fnRegexCaptureNamed() *object.Foreign {
+	return &object.Foreign{
+		Name: "captureNamed",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			str, re, errObj := unpackRegexArgs(ctx, args)
+			if errObj != nil {
+				return errObj
+			}
+
+			groups := captureGroups(re, str)
+			if groups == nil {
+				return ctx.Nil()
+			}
+
+			result := &object.Map{Pairs: map[object.MapKey]object.MapPair{}}
+			for i, name := range re.SubexpNames() {
+				if name == "" {
+					continue
+				}
+				putObj(result, name, groups[i])
+			}
+
+			return result
+		},
+	}
+}
+
+func unpackRegexArgs(ctx object.EvaluatorContext, args []object.Object) (string, *regexp.Regexp, object.Object) {
+	if len(args) != 2 {
+		return "", nil, ctx.NewError("wrong number of arguments. got=%d, want=2",
+			len(args))
+	}
+
+	str, err := unpackString(args[0], "str")
+	if err != nil {
+		return "", nil, ctx.NewError(err.Error())
+	}
+
+	pattern, err := unpackString(args[1], "pattern")
+	if err != nil {
+		return "", nil, ctx.NewError(err.Error())
+	}
+
+	re, err := ctx.CompileRegex(pattern)
+	if err != nil {
+		return "", nil, ctx.NewError(err.Error())
+	}
+
+	return str, re, nil
+}
+
+// captureGroups returns the first match of re in str followed by each submatch, or nil when
+// there is no match. Groups that did not participate in the match are returned as nil rather
+// than as empty strings so callers can tell the two apart.
+func captureGroups(re *regexp.Regexp, str string) []object.Object {
+	loc := re.FindStringSubmatchIndex(str)
+	if loc == nil {
+		return nil
+	}
+
+	groups := make([]object.Object, len(loc)/2)
+	for i := range groups {
+		start, end := loc[2*i], loc[2*i+1]
+		if start < 0 {
+			groups[i] = object.NIL
+		} else {
+			groups[i] = &object.String{Value: str[start:end]}
+		}
+	}
+	return groups
+}
+
 func fnRegexFindAllGroups() *object.Foreign {
 	return &object.Foreign{
 		Name: "findAllGroups",
@@ -173,7 +298,7 @@ func fnRegexFindAllGroups() *object.Foreign {
 				return ctx.NewError(err.Error())
 			}
 
-			re, err := regexp.Compile(pattern)
+			re, err := ctx.CompileRegex(pattern)
 			if err != nil {
 				return ctx.NewError(err.Error())
 			}
@@ -218,7 +343,7 @@ func fnRegexReplaceAll() *object.Foreign {
 				return ctx.NewError(err.Error())
 			}
 
-			re, err := regexp.Compile(pattern)
+			re, err := ctx.CompileRegex(pattern)
 			if err != nil {
 				return ctx.NewError(err.Error())
 			}
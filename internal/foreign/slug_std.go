@@ -1,8 +1,12 @@
 package foreign
 
 import (
+	"fmt"
+	"hash/fnv"
 	"slug/internal/dec64"
 	"slug/internal/object"
+	"strings"
+	"time"
 )
 
 func fnStdType() *object.Foreign {
@@ -32,6 +36,31 @@ func fnStdType() *object.Foreign {
 	}}
 }
 
+func fnStdIsa() *object.Foreign {
+	return &object.Foreign{Name: "isa", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=2",
+				len(args))
+		}
+
+		value, ok := resolveBindingValue(args[0])
+		if !ok {
+			return ctx.NewError("isa could not resolve its value argument")
+		}
+		schemaArg, ok := resolveBindingValue(args[1])
+		if !ok {
+			return ctx.NewError("isa could not resolve its schema argument")
+		}
+		schema, ok := schemaArg.(*object.StructSchema)
+		if !ok {
+			return ctx.NewError("second argument to `isa` must be a struct schema, got=%s", schemaArg.Type())
+		}
+
+		structVal, ok := value.(*object.StructValue)
+		return ctx.NativeBoolToBooleanObject(ok && structVal.Schema == schema)
+	}}
+}
+
 func typeTagForObject(obj object.Object) (string, bool) {
 	switch obj.Type() {
 	case object.NIL_OBJ:
@@ -58,6 +87,16 @@ func typeTagForObject(obj object.Object) (string, bool) {
 		return "task", true
 	case object.CHANNEL_OBJ:
 		return "channel", true
+	case object.STRING_BUILDER_OBJ:
+		return "builder", true
+	case object.ARRAY_OBJ:
+		return "array", true
+	case object.DICT_OBJ:
+		return "dict", true
+	case object.COUNTER_OBJ:
+		return "counter", true
+	case object.SET_OBJ:
+		return "set", true
 	case object.STRUCT_SCHEMA_OBJ:
 		return "struct", true
 	default:
@@ -120,7 +159,7 @@ func fnStdKeys() *object.Foreign {
 			switch obj := args[0].(type) {
 			case *object.Map:
 				keys := make([]object.Object, 0, len(obj.Pairs))
-				for _, pair := range obj.Pairs {
+				for _, pair := range obj.OrderedPairs() {
 					keys = append(keys, pair.Key)
 				}
 				return &object.List{Elements: keys}
@@ -146,6 +185,291 @@ func fnStdKeys() *object.Foreign {
 	}
 }
 
+func fnStdValues() *object.Foreign {
+	return &object.Foreign{
+		Name: "values",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			obj, ok := args[0].(*object.Map)
+			if !ok {
+				return ctx.NewError("argument to `values` must be a map, got=%s", args[0].Type())
+			}
+
+			values := make([]object.Object, 0, len(obj.Pairs))
+			for _, pair := range obj.OrderedPairs() {
+				values = append(values, pair.Value)
+			}
+			return &object.List{Elements: values}
+		},
+	}
+}
+
+func fnStdEntries() *object.Foreign {
+	return &object.Foreign{
+		Name: "entries",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			obj, ok := args[0].(*object.Map)
+			if !ok {
+				return ctx.NewError("argument to `entries` must be a map, got=%s", args[0].Type())
+			}
+
+			entries := make([]object.Object, 0, len(obj.Pairs))
+			for _, pair := range obj.OrderedPairs() {
+				entries = append(entries, &object.List{Elements: []object.Object{pair.Key, pair.Value}})
+			}
+			return &object.List{Elements: entries}
+		},
+	}
+}
+
+func fnStdForeach() *object.Foreign {
+	return &object.Foreign{
+		Name: "foreach",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			var items []object.Object
+			switch coll := args[0].(type) {
+			case *object.List:
+				items = coll.Elements
+			case *object.String:
+				for _, r := range coll.Value {
+					items = append(items, &object.String{Value: string(r)})
+				}
+			case *object.Bytes:
+				for _, b := range coll.Value {
+					items = append(items, &object.Number{Value: dec64.FromInt64(int64(b))})
+				}
+			case *object.Map:
+				for _, pair := range coll.OrderedPairs() {
+					items = append(items, &object.List{Elements: []object.Object{pair.Key, pair.Value}})
+				}
+			default:
+				return ctx.NewError("first argument to `foreach` must be a list, string, bytes or map, got=%s", args[0].Type())
+			}
+
+			fn := args[1]
+			withIndex := !acceptsArgCount(fn, 1) && acceptsArgCount(fn, 2)
+			for i, item := range items {
+				callArgs := []object.Object{item}
+				if withIndex {
+					callArgs = append(callArgs, &object.Number{Value: dec64.FromInt(i)})
+				}
+				if result := ctx.ApplyFunction(0, "foreach", fn, callArgs, nil); result != nil && result.Type() == object.ERROR_OBJ {
+					return result
+				}
+			}
+			return object.NIL
+		},
+	}
+}
+
+func fnStdRetry() *object.Foreign {
+	return &object.Foreign{
+		Name: "retry",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			attempts, ok := args[1].(*object.Number)
+			if !ok || attempts.Value.ToInt64() < 1 {
+				return ctx.NewError("attempts for `retry` must be a number >= 1, got %s", args[1].Inspect())
+			}
+			delayArg, ok := args[2].(*object.Number)
+			if !ok || delayArg.Value.ToInt64() < 0 {
+				return ctx.NewError("delay for `retry` must be a non-negative number, got %s", args[2].Inspect())
+			}
+
+			delay := time.Duration(delayArg.Value.ToInt64()) * time.Millisecond
+			var result object.Object
+			for attempt := int64(1); ; attempt++ {
+				result = ctx.ApplyFunction(0, "retry", args[0], nil, nil)
+				if _, failed := result.(*object.RuntimeError); !failed || attempt >= attempts.Value.ToInt64() {
+					return result
+				}
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-ctx.Context().Done():
+					timer.Stop()
+					return result
+				}
+				delay *= 2
+			}
+		},
+	}
+}
+
+func fnStdDispatch() *object.Foreign {
+	return &object.Foreign{
+		Name: "dispatch",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+
+			key, ok := object.HashableKey(args[0])
+			if !ok {
+				return ctx.NewError("unusable as map key: %s", args[0].Type())
+			}
+			handlers, ok := args[1].(*object.Map)
+			if !ok {
+				return ctx.NewError("second argument to `dispatch` must be a map, got %s", args[1].Type())
+			}
+
+			handler := args[2]
+			if pair, ok := handlers.Pairs[key.MapKey()]; ok {
+				handler = pair.Value
+			}
+
+			// Handlers may ignore the value or take it as their only argument
+			var callArgs []object.Object
+			if !acceptsArgCount(handler, 0) {
+				callArgs = []object.Object{args[0]}
+			}
+			return ctx.ApplyFunction(0, "dispatch", handler, callArgs, nil)
+		},
+	}
+}
+
+func fnStdFind() *object.Foreign {
+	return &object.Foreign{
+		Name: "find",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			list, idx, err := findFirst(ctx, "find", args, true)
+			if err != nil {
+				return err
+			}
+			if idx < 0 {
+				return object.NIL
+			}
+			return list.Elements[idx]
+		},
+	}
+}
+
+func fnStdFindIndex() *object.Foreign {
+	return &object.Foreign{
+		Name: "findIndex",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			_, idx, err := findFirst(ctx, "findIndex", args, true)
+			if err != nil {
+				return err
+			}
+			return &object.Number{Value: dec64.FromInt(idx)}
+		},
+	}
+}
+
+func fnStdAny() *object.Foreign {
+	return &object.Foreign{
+		Name: "any",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			_, idx, err := findFirst(ctx, "any", args, true)
+			if err != nil {
+				return err
+			}
+			return ctx.NativeBoolToBooleanObject(idx >= 0)
+		},
+	}
+}
+
+func fnStdAll() *object.Foreign {
+	return &object.Foreign{
+		Name: "all",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			_, idx, err := findFirst(ctx, "all", args, false)
+			if err != nil {
+				return err
+			}
+			return ctx.NativeBoolToBooleanObject(idx < 0)
+		},
+	}
+}
+
+// findFirst calls the predicate args[1] on each element of the list args[0] and returns the index
+// of the first element whose result has the wanted truthiness, or -1. The predicate is not called
+// again once an element is found, and an error from it stops the search.
+func findFirst(ctx object.EvaluatorContext, name string, args []object.Object, want bool) (*object.List, int, object.Object) {
+	if len(args) != 2 {
+		return nil, -1, ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	list, ok := args[0].(*object.List)
+	if !ok {
+		return nil, -1, ctx.NewError("first argument to `%s` must be a LIST, got=%s", name, args[0].Type())
+	}
+	for i, el := range list.Elements {
+		result := ctx.ApplyFunction(0, name, args[1], []object.Object{el}, nil)
+		if result != nil && result.Type() == object.ERROR_OBJ {
+			return nil, -1, result
+		}
+		if isTruthy(result) == want {
+			return list, i, nil
+		}
+	}
+	return list, -1, nil
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case nil, object.NIL, object.FALSE:
+		return false
+	default:
+		return true
+	}
+}
+
+// acceptsArgCount reports whether fn has an implementation callable with n positional arguments.
+func acceptsArgCount(fn object.Object, n int) bool {
+	switch f := fn.(type) {
+	case *object.Function:
+		return f.Signature.Min <= n && n <= f.Signature.Max
+	case *object.Foreign:
+		return f.Signature.Min <= n && n <= f.Signature.Max
+	case *object.FunctionGroup:
+		for sig := range f.Functions {
+			if sig.Min <= n && n <= sig.Max {
+				return true
+			}
+		}
+		for _, d := range f.Delegates {
+			if acceptsArgCount(d, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fnStdFrozen() *object.Foreign {
+	return &object.Foreign{
+		Name: "frozen",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			return object.DeepCopy(args[0])
+		},
+	}
+}
+
+// fnStdClone is frozen under another name, both are deep copies and differ only in intent.
+func fnStdClone() *object.Foreign {
+	clone := fnStdFrozen()
+	clone.Name = "clone"
+	return clone
+}
+
 func fnStdSym() *object.Foreign {
 	return &object.Foreign{
 		Name: "sym",
@@ -178,6 +502,38 @@ func fnStdLabel() *object.Foreign {
 	}
 }
 
+func fnStdHashKey() *object.Foreign {
+	return &object.Foreign{
+		Name: "hashKey",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			// Hash the canonical form of the value rather than its MapKey: symbol ids
+			// depend on interning order and number keys drop the fraction.
+			h := fnv.New64a()
+			h.Write([]byte(args[0].Type()))
+			h.Write([]byte{0})
+			switch v := args[0].(type) {
+			case *object.Number:
+				h.Write([]byte(v.Value.String()))
+			case *object.String:
+				h.Write([]byte(v.Value))
+			case *object.Boolean:
+				h.Write([]byte(fmt.Sprint(v.Value)))
+			case *object.Symbol:
+				h.Write([]byte(v.Name))
+			case *object.Bytes:
+				h.Write(v.Value)
+			default:
+				return ctx.NewError("argument to `hashKey` must be hashable, got=%s", args[0].Type())
+			}
+			return &object.String{Value: fmt.Sprintf("%016x", h.Sum64())}
+		},
+	}
+}
+
 // map functions
 // -------------
 
@@ -194,7 +550,7 @@ func fnStdGet() *object.Foreign {
 			}
 
 			mapObj := args[0].(*object.Map)
-			key, ok := args[1].(object.Hashable)
+			key, ok := object.HashableKey(args[1])
 			if !ok {
 				return ctx.NewError("unusable as map key: %s", args[1].Type())
 			}
@@ -222,20 +578,12 @@ func fnStdPut() *object.Foreign {
 			}
 
 			mapObj := args[0].(*object.Map)
-			key, ok := args[1].(object.Hashable)
+			key, ok := object.HashableKey(args[1])
 			if !ok {
 				return ctx.NewError("unusable as map key: %s", args[1].Type())
 			}
 
-			newPairs := make(map[object.MapKey]object.MapPair)
-			for k, v := range mapObj.Pairs {
-				newPairs[k] = v
-			}
-
-			mapKey := key.MapKey()
-			newPairs[mapKey] = object.MapPair{Key: args[1], Value: args[2]}
-
-			return &object.Map{Pairs: newPairs}
+			return mapObj.Copy().Put(key, args[2])
 		},
 	}
 }
@@ -253,20 +601,12 @@ func fnStdRemove() *object.Foreign {
 			}
 
 			mapObj := args[0].(*object.Map)
-			key, ok := args[1].(object.Hashable)
+			key, ok := object.HashableKey(args[1])
 			if !ok {
 				return ctx.NewError("unusable as map key: %s", args[1].Type())
 			}
 
-			newPairs := make(map[object.MapKey]object.MapPair)
-			for k, v := range mapObj.Pairs {
-				newPairs[k] = v
-			}
-
-			mapKey := key.MapKey()
-			delete(newPairs, mapKey)
-
-			return &object.Map{Pairs: newPairs}
+			return mapObj.Copy().Delete(key.MapKey())
 		},
 	}
 }
@@ -344,16 +684,35 @@ func fnStdParseNumber() *object.Foreign {
 	return &object.Foreign{
 		Name: "parseNumber",
 		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			if len(args) != 3 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=3", len(args))
 			}
 
 			str, ok := args[0].(*object.String)
 			if !ok {
 				return ctx.NewError("argument to `parseNumber` must be STRING, got %s", args[0].Type())
 			}
+			group, ok := args[1].(*object.String)
+			if !ok {
+				return ctx.NewError("group separator for `parseNumber` must be STRING, got %s", args[1].Type())
+			}
+			decimal, ok := args[2].(*object.String)
+			if !ok || decimal.Value == "" || decimal.Value == group.Value {
+				return ctx.NewError("decimal separator for `parseNumber` must be a non-empty STRING unlike the group separator, got %s", args[2].Inspect())
+			}
+
+			value := str.Value
+			if group.Value != "" {
+				value = strings.ReplaceAll(value, group.Value, "")
+			}
+			if decimal.Value != "." {
+				if strings.Contains(value, ".") {
+					return ctx.NewError("could not convert string to number: unexpected '.' in %q", str.Value)
+				}
+				value = strings.ReplaceAll(value, decimal.Value, ".")
+			}
 
-			n, err := dec64.FromString(str.Value)
+			n, err := dec64.FromString(value)
 			if err != nil {
 				return ctx.NewError("could not convert string to number: %s", err)
 			}
@@ -0,0 +1,153 @@
+package foreign
+
+import (
+	"encoding/hex"
+	"math"
+	"slug/internal/ast"
+	"slug/internal/object"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func fnStdMemoize() *object.Foreign {
+	return &object.Foreign{
+		Name: "memoize",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.FunctionGroup, *object.Foreign:
+				return Memoize(args[0])
+			default:
+				return ctx.NewError("argument to `memoize` must be a function, got=%s", args[0].Type())
+			}
+		},
+	}
+}
+
+// Memoize wraps fn in a foreign function that caches its results by argument value. Calls whose
+// arguments are not plain values (functions, tasks, ...) and calls that fail are not cached. The
+// wrapper only takes positional arguments. It keeps the signature and tags of a plain function so
+// it binds and dispatches like the function it replaces.
+func Memoize(fn object.Object) *object.Foreign {
+	var cache sync.Map
+
+	wrapper := &object.Foreign{
+		Name:      "memoized",
+		Signature: ast.FSig{Min: 0, Max: math.MaxInt, IsVariadic: true},
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			key, ok := memoKey(args)
+			if ok {
+				if cached, hit := cache.Load(key); hit {
+					return cached.(object.Object)
+				}
+			}
+
+			result := ctx.ApplyFunction(0, "memoized", fn, args, nil)
+
+			switch result.(type) {
+			case *object.Error, *object.RuntimeError:
+				return result
+			}
+			if ok {
+				cache.Store(key, result)
+			}
+			return result
+		},
+	}
+
+	if f, ok := fn.(*object.Function); ok {
+		wrapper.Signature = f.Signature
+		wrapper.Tags = f.Tags
+	}
+	return wrapper
+}
+
+func fnStdOnce() *object.Foreign {
+	return &object.Foreign{
+		Name: "once",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.FunctionGroup, *object.Foreign:
+				return Once(args[0])
+			default:
+				return ctx.NewError("argument to `once` must be a function, got=%s", args[0].Type())
+			}
+		},
+	}
+}
+
+// Once wraps fn in a foreign function of no arguments that calls fn the first time it is
+// invoked and returns that same result, error included, on every later call. Tasks that call
+// the wrapper while fn is still running wait for it to finish.
+func Once(fn object.Object) *object.Foreign {
+	var once sync.Once
+	var result object.Object
+
+	return &object.Foreign{
+		Name:      "once",
+		Signature: ast.FSig{Min: 0, Max: 0},
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			once.Do(func() {
+				result = ctx.ApplyFunction(0, "once", fn, nil, nil)
+			})
+			return result
+		},
+	}
+}
+
+// memoKey encodes args as a cache key, reporting false if any argument is not a plain value.
+func memoKey(args []object.Object) (string, bool) {
+	var sb strings.Builder
+	for _, arg := range args {
+		if !writeMemoKey(&sb, arg) {
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+func writeMemoKey(sb *strings.Builder, obj object.Object) bool {
+	switch o := obj.(type) {
+	case *object.Nil, *object.Boolean, *object.Symbol:
+		sb.WriteString(string(o.Type()))
+		sb.WriteByte(':')
+		sb.WriteString(o.Inspect())
+	case *object.Number:
+		sb.WriteString("NUMBER:")
+		sb.WriteString(o.Value.String())
+	case *object.String:
+		sb.WriteString("STRING:")
+		sb.WriteString(strconv.Itoa(len(o.Value)))
+		sb.WriteByte(':')
+		sb.WriteString(o.Value)
+	case *object.Bytes:
+		sb.WriteString("BYTES:")
+		sb.WriteString(hex.EncodeToString(o.Value))
+	case *object.List:
+		sb.WriteString("LIST:")
+		sb.WriteString(strconv.Itoa(len(o.Elements)))
+		for _, el := range o.Elements {
+			if !writeMemoKey(sb, el) {
+				return false
+			}
+		}
+	case *object.Map:
+		sb.WriteString("MAP:")
+		sb.WriteString(strconv.Itoa(len(o.Pairs)))
+		for _, pair := range o.OrderedPairs() {
+			if !writeMemoKey(sb, pair.Key) || !writeMemoKey(sb, pair.Value) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	sb.WriteByte(';')
+	return true
+}
@@ -110,3 +110,135 @@ func fnStringToLower() *object.Foreign {
 	},
 	}
 }
+
+func fnStringTrimLeft() *object.Foreign {
+	return &object.Foreign{Name: "trimLeft", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+
+		switch arg := args[0].(type) {
+		case *object.String:
+			return &object.String{Value: strings.TrimLeftFunc(arg.Value, unicode.IsSpace)}
+		case *object.Nil:
+			return arg
+		default:
+			return ctx.NewError("argument to `trimLeft` not supported, got %s", args[0].Type())
+		}
+	},
+	}
+}
+
+func fnStringTrimRight() *object.Foreign {
+	return &object.Foreign{Name: "trimRight", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+
+		switch arg := args[0].(type) {
+		case *object.String:
+			return &object.String{Value: strings.TrimRightFunc(arg.Value, unicode.IsSpace)}
+		case *object.Nil:
+			return arg
+		default:
+			return ctx.NewError("argument to `trimRight` not supported, got %s", args[0].Type())
+		}
+	},
+	}
+}
+
+func fnStringPadLeft() *object.Foreign {
+	return &object.Foreign{Name: "padLeft", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		str, padding, errObj := unpackPadArgs(ctx, "padLeft", args)
+		if errObj != nil {
+			return errObj
+		}
+		return &object.String{Value: padding + str}
+	},
+	}
+}
+
+func fnStringPadRight() *object.Foreign {
+	return &object.Foreign{Name: "padRight", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		str, padding, errObj := unpackPadArgs(ctx, "padRight", args)
+		if errObj != nil {
+			return errObj
+		}
+		return &object.String{Value: str + padding}
+	},
+	}
+}
+
+// unpackPadArgs validates the (str, width, fill) arguments shared by padLeft and padRight and
+// returns the input string along with the padding required to reach width. Width is measured
+// in runes so it agrees with `len` and string indexing.
+func unpackPadArgs(ctx object.EvaluatorContext, fnName string, args []object.Object) (string, string, object.Object) {
+	if len(args) != 3 {
+		return "", "", ctx.NewError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+
+	str, err := unpackString(args[0], fnName)
+	if err != nil {
+		return "", "", ctx.NewError(err.Error())
+	}
+	width, err := unpackNumber(args[1], fnName)
+	if err != nil {
+		return "", "", ctx.NewError(err.Error())
+	}
+	fill, err := unpackString(args[2], fnName)
+	if err != nil {
+		return "", "", ctx.NewError(err.Error())
+	}
+	if utf8.RuneCountInString(fill) != 1 {
+		return "", "", ctx.NewError("fill argument to `%s` must be a single character, got %q", fnName, fill)
+	}
+
+	missing := int(width) - utf8.RuneCountInString(str)
+	if missing <= 0 {
+		return str, "", nil
+	}
+	return str, strings.Repeat(fill, missing), nil
+}
+
+func fnStringBuilder() *object.Foreign {
+	return &object.Foreign{Name: "builder", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 0 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=0", len(args))
+		}
+		return &object.StringBuilder{}
+	},
+	}
+}
+
+func fnStringAppend() *object.Foreign {
+	return &object.Foreign{Name: "append", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+		}
+		sb, ok := args[0].(*object.StringBuilder)
+		if !ok {
+			return ctx.NewError("first argument to `append` must be a builder, got %s", args[0].Type())
+		}
+		s, ok := args[1].(*object.String)
+		if !ok {
+			return ctx.NewError("second argument to `append` must be a string, got %s", args[1].Type())
+		}
+		sb.Append(s.Value)
+		return sb
+	},
+	}
+}
+
+func fnStringBuild() *object.Foreign {
+	return &object.Foreign{Name: "build", Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+		}
+		sb, ok := args[0].(*object.StringBuilder)
+		if !ok {
+			return ctx.NewError("argument to `build` must be a builder, got %s", args[0].Type())
+		}
+		return &object.String{Value: sb.String()}
+	},
+	}
+}
@@ -59,8 +59,15 @@ func fnTimeSleep() *object.Foreign {
 				return ctx.NewError("argument to `sleep` must be non-negative, got=%v", intArg.Value)
 			}
 
-			// Pause execution for the specified duration
-			time.Sleep(time.Duration(millis) * time.Millisecond)
+			// Pause execution for the specified duration. A cancelled task wakes early with an
+			// error so it unwinds, rather than carrying on with every later sleep returning at once
+			timer := time.NewTimer(time.Duration(millis) * time.Millisecond)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Context().Done():
+				return ctx.NewError("sleep interrupted: task cancelled")
+			}
 
 			return ctx.Nil()
 		},
@@ -99,3 +106,54 @@ func fnTimeFmtClock() *object.Foreign {
 		},
 	}
 }
+
+func fnTimeParseTime() *object.Foreign {
+	return &object.Foreign{
+		Name: "parseTime",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			str, err := unpackString(args[0], "str")
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			layout, err := unpackString(args[1], "layout")
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			t, err := time.Parse(layout, str)
+			if err != nil {
+				return ctx.NewError("cannot parse time %q with layout %q: %s", str, layout, err.Error())
+			}
+
+			return &object.Number{Value: dec64.FromInt64(t.UnixMilli())}
+		},
+	}
+}
+
+func fnTimeFormatTime() *object.Foreign {
+	return &object.Foreign{
+		Name: "formatTime",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			millis, err := unpackNumber(args[0], "millis")
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			layout, err := unpackString(args[1], "layout")
+			if err != nil {
+				return ctx.NewError(err.Error())
+			}
+
+			return &object.String{Value: time.UnixMilli(millis).UTC().Format(layout)}
+		},
+	}
+}
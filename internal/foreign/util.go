@@ -51,10 +51,7 @@ func unpackNumber(arg object.Object, argName string) (int64, error) {
 
 func putObj(resultMap *object.Map, key string, val object.Object) {
 	keyStr := object.InternSymbol(key)
-	resultMap.Pairs[(keyStr).MapKey()] = object.MapPair{
-		Key:   keyStr,
-		Value: val,
-	}
+	resultMap.Put(keyStr, val)
 }
 
 func GetObj(m *object.Map, key object.MapKey) (object.Object, bool) {
@@ -20,6 +20,10 @@ func (g *GeneralTokenizer) NextToken() token.Token {
 
 	startPosition := g.lexer.position // Record the current position as the start of the token
 
+	if tok, ok := g.lexer.readCustomOperator(); ok {
+		return tok
+	}
+
 	switch g.lexer.ch {
 	case '\n':
 		// If we are inside delimiters, treat newline as whitespace
@@ -50,9 +54,9 @@ func (g *GeneralTokenizer) NextToken() token.Token {
 	case '=':
 		tok = g.lexer.handleCompoundToken2(token.ASSIGN, '=', token.EQ, '>', token.ROCKET)
 	case '+':
-		tok = g.lexer.handleCompoundToken(token.PLUS, ':', token.PREPEND_ITEM)
+		tok = g.lexer.handleCompoundToken2(token.PLUS, ':', token.PREPEND_ITEM, '=', token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, g.lexer.ch, startPosition)
+		tok = g.lexer.handleCompoundToken(token.MINUS, '=', token.MINUS_ASSIGN)
 	case '!':
 		tok = g.lexer.handleCompoundToken(token.BANG, '=', token.NOT_EQ)
 	case '/':
@@ -71,13 +75,16 @@ func (g *GeneralTokenizer) NextToken() token.Token {
 				return token.Token{Type: token.ILLEGAL, Literal: err.Error(), Position: startPosition}
 			}
 			return g.NextToken()
+		} else if g.lexer.peekChar() == '=' {
+			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/=", Position: startPosition}
+			g.lexer.readChar()
 		} else {
 			tok = newToken(token.SLASH, g.lexer.ch, startPosition)
 		}
 	case '*':
-		tok = newToken(token.ASTERISK, g.lexer.ch, startPosition)
+		tok = g.lexer.handleCompoundToken(token.ASTERISK, '=', token.ASTERISK_ASSIGN)
 	case '%':
-		tok = newToken(token.PERCENT, g.lexer.ch, startPosition)
+		tok = g.lexer.handleCompoundToken(token.PERCENT, '=', token.PERCENT_ASSIGN)
 	case '~':
 		tok = newToken(token.COMPLEMENT, g.lexer.ch, startPosition)
 	case '&':
@@ -126,9 +133,17 @@ func (g *GeneralTokenizer) NextToken() token.Token {
 			tok = token.Token{Type: token.NOT_IMPLEMENTED, Literal: "???", Position: startPosition}
 			g.lexer.readChar()
 			g.lexer.readChar()
+		} else if g.lexer.peekChar() == '?' {
+			tok = token.Token{Type: token.NIL_COALESCE, Literal: "??", Position: startPosition}
+			g.lexer.readChar()
+		} else if g.lexer.peekChar() == '.' {
+			tok = token.Token{Type: token.OPTIONAL_CHAIN, Literal: "?.", Position: startPosition}
+			g.lexer.readChar()
 		} else {
 			return newToken(token.ILLEGAL, g.lexer.ch, startPosition)
 		}
+	case '#':
+		tok = g.lexer.handleCompoundToken(token.ILLEGAL, '{', token.ANON_STRUCT)
 	case '{':
 		tok = g.lexer.handleCompoundToken2(token.LBRACE, '{', token.INTERPOLATION_START, '|', token.MATCH_KEYS_EXACT)
 		if tok.Type == token.INTERPOLATION_START {
@@ -67,7 +67,11 @@ func (l *Lexer) hasInterpolationReturnMode() bool {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	return l.currentMode.NextToken()
+	tok := l.currentMode.NextToken()
+	if tok.End == 0 {
+		tok.End = max(l.position, tok.Position)
+	}
+	return tok
 }
 
 func (l *Lexer) handleCompoundToken(
@@ -116,6 +120,9 @@ func (l *Lexer) skipWhitespace() {
 		case ' ', '\t', '\r':
 			l.readChar()
 		case '#':
+			if l.peekChar() == '{' {
+				return // anonymous struct literal, not a comment
+			}
 			l.skipToLineEnd()
 		case '/':
 			if l.peekChar() == '/' {
@@ -394,6 +401,65 @@ func (l *Lexer) readByteArrayLiteral() (string, bool) {
 	return hexStr, true
 }
 
+// operatorChars are the characters a custom operator is spelled with.
+const operatorChars = "<>=!+-*/%&|^~?$"
+
+// builtinOperators are the spellings the language already uses, which a custom
+// operator cannot take.
+var builtinOperators = map[string]bool{
+	"=": true, "==": true, "=>": true, "!": true, "!=": true,
+	"+": true, "+=": true, "-": true, "-=": true, "*": true, "*=": true,
+	"/": true, "/=": true, "/>": true, "%": true, "%=": true,
+	"<": true, "<=": true, "<<": true, ">": true, ">=": true, ">>": true,
+	"~": true, "&": true, "&&": true, "|": true, "||": true, "^": true,
+	"??": true, "???": true,
+}
+
+// IsCustomOperator reports whether op can be declared with @operator: a run of
+// operator characters that is not a built-in operator and does not start a
+// comment.
+func IsCustomOperator(op string) bool {
+	if op == "" || builtinOperators[op] || strings.HasPrefix(op, "//") || strings.HasPrefix(op, "/*") {
+		return false
+	}
+	for _, ch := range op {
+		if !strings.ContainsRune(operatorChars, ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// readCustomOperator reads a custom operator at the current position. It has to
+// stand apart, with whitespace on both sides, so runs such as the `>-` in
+// `a >-1` still read as built-in operators.
+func (l *Lexer) readCustomOperator() (token.Token, bool) {
+	if l.position == 0 || !isOperatorSpace(l.input[l.position-1]) {
+		return token.Token{}, false
+	}
+	end := l.position
+	for end < len(l.input) && strings.IndexByte(operatorChars, l.input[end]) >= 0 {
+		end++
+	}
+	if end < len(l.input) && !isOperatorSpace(l.input[end]) {
+		return token.Token{}, false
+	}
+	op := l.input[l.position:end]
+	if !IsCustomOperator(op) {
+		return token.Token{}, false
+	}
+
+	start := l.position
+	for l.position < end {
+		l.readChar()
+	}
+	return token.Token{Type: token.CUSTOM_OPERATOR, Literal: op, Position: start, End: end}, true
+}
+
+func isOperatorSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
 // Unicode-aware helpers
 func isLetter(ch rune) bool {
 	// Letters, underscore, and categories like Letter and Mark to support identifiers like café,变量
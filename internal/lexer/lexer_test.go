@@ -285,6 +285,145 @@ val x = 1`
 	}
 }
 
+func TestAnonStructToken(t *testing.T) {
+	input := `# comment
+#{x: 1}`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.NEWLINE, "\n"},
+		{token.ANON_STRUCT, "#{"},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.NUMBER, "1"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected=%q '%q', got=%q: '%q'",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestNilCoalesceToken(t *testing.T) {
+	input := `a ?? b ??? c`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.NIL_COALESCE, "??"},
+		{token.IDENT, "b"},
+		{token.NOT_IMPLEMENTED, "???"},
+		{token.IDENT, "c"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected=%q '%q', got=%q: '%q'",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestCompoundAssignTokens(t *testing.T) {
+	input := `x += 1 -= 2 *= 3 /= 4 %= 5 +: 6`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.NUMBER, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.NUMBER, "2"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.NUMBER, "3"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.NUMBER, "4"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.NUMBER, "5"},
+		{token.PREPEND_ITEM, "+:"},
+		{token.NUMBER, "6"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected=%q '%q', got=%q: '%q'",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestCustomOperatorTokens(t *testing.T) {
+	input := "a <> b <+> c ** d >= e >-1 x --y // comment\nz $ w"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.CUSTOM_OPERATOR, "<>"},
+		{token.IDENT, "b"},
+		{token.CUSTOM_OPERATOR, "<+>"},
+		{token.IDENT, "c"},
+		{token.CUSTOM_OPERATOR, "**"},
+		{token.IDENT, "d"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "e"},
+		{token.GT, ">"},
+		{token.MINUS, "-"},
+		{token.NUMBER, "1"},
+		{token.IDENT, "x"},
+		{token.MINUS, "-"},
+		{token.MINUS, "-"},
+		{token.IDENT, "y"},
+		{token.NEWLINE, "\n"},
+		{token.IDENT, "z"},
+		{token.CUSTOM_OPERATOR, "$"},
+		{token.IDENT, "w"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected=%q '%q', got=%q: '%q'",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	for op, want := range map[string]bool{"<>": true, "|>": true, "<=>": true, "<=": false, "//": false, "/*x": false, "a+": false, "": false} {
+		if got := IsCustomOperator(op); got != want {
+			t.Errorf("IsCustomOperator(%q) = %t, want %t", op, got, want)
+		}
+	}
+}
+
 func TestDocCommentFormatError(t *testing.T) {
 	input := `/**
 not ok
@@ -362,3 +501,34 @@ string
 		}
 	}
 }
+
+func TestTokenEndPositions(t *testing.T) {
+	input := `add(12, x) >= 0x"ff"`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedText string
+	}{
+		{token.IDENT, "add"},
+		{token.LPAREN, "("},
+		{token.NUMBER, "12"},
+		{token.COMMA, ","},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.GT_EQ, ">="},
+		{token.BYTES, `0x"ff"`},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if got := input[tok.Position:tok.End]; got != tt.expectedText {
+			t.Fatalf("tests[%d] - expected span %q, got=%q", i, tt.expectedText, got)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package object
+
+import "fmt"
+
+// Array is a growable list that is updated in place, for code that builds results
+// incrementally. Like StringBuilder it has no locking and must stay within one task;
+// ToList hands the contents over as an ordinary immutable list.
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string  { return fmt.Sprintf("<array %d>", len(a.Elements)) }
+
+// Index resolves idx against the array, counting negative indexes from the end.
+func (a *Array) Index(idx int) (int, bool) {
+	if idx < 0 {
+		idx += len(a.Elements)
+	}
+	return idx, idx >= 0 && idx < len(a.Elements)
+}
+
+func (a *Array) ToList() *List {
+	elements := make([]Object, len(a.Elements))
+	copy(elements, a.Elements)
+	return &List{Elements: elements}
+}
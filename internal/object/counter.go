@@ -0,0 +1,21 @@
+package object
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Counter is an integer updated atomically, so tasks can aggregate into it without
+// a channel. Unlike the other mutable values it is safe to share between tasks.
+type Counter struct {
+	value atomic.Int64
+}
+
+func (c *Counter) Type() ObjectType { return COUNTER_OBJ }
+func (c *Counter) Inspect() string  { return fmt.Sprintf("<counter %d>", c.Load()) }
+
+func (c *Counter) Add(delta int64) int64 { return c.value.Add(delta) }
+
+func (c *Counter) Load() int64 { return c.value.Load() }
+
+func (c *Counter) Store(v int64) { c.value.Store(v) }
@@ -0,0 +1,35 @@
+package object
+
+import "fmt"
+
+// Dict is a map that is updated in place, for hot paths that accumulate entries.
+// It keeps insertion order like Map, has no locking and must stay within one task;
+// ToMap hands the contents over as an ordinary immutable map.
+type Dict struct {
+	entries Map
+}
+
+func (d *Dict) Type() ObjectType { return DICT_OBJ }
+func (d *Dict) Inspect() string  { return fmt.Sprintf("<dict %d>", d.Len()) }
+
+func (d *Dict) Len() int { return len(d.entries.Pairs) }
+
+func (d *Dict) Set(k Hashable, v Object) { d.entries.Put(k, v) }
+
+func (d *Dict) Get(k Hashable) (Object, bool) { return d.entries.Get(k) }
+
+func (d *Dict) Delete(k Hashable) { d.entries.Delete(k.MapKey()) }
+
+// Keys returns the keys of the dict in insertion order.
+func (d *Dict) Keys() []Object {
+	pairs := d.entries.OrderedPairs()
+	keys := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = pair.Key
+	}
+	return keys
+}
+
+func (d *Dict) ToMap() *Map {
+	return d.entries.Copy()
+}
@@ -3,6 +3,7 @@ package object
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"runtime"
 	"slug/internal/ast"
 	"sync"
@@ -11,6 +12,13 @@ import (
 
 var nextID atomic.Uint64
 
+// bindingEpoch advances whenever a scope that cached lookups depend on gains or
+// loses a binding, which invalidates every cached resolution at once.
+var bindingEpoch atomic.Uint64
+
+// minCachedHops is how many outer scopes a lookup must walk before it is cached.
+const minCachedHops = 2
+
 type Environment struct {
 	ID        uint64
 	Bindings  map[string]*Binding
@@ -24,7 +32,18 @@ type Environment struct {
 	Limit                int
 	IsThreadNurseryScope bool // marks a scope that can own spawned tasks
 
-	mu sync.RWMutex
+	mu       sync.RWMutex
+	resolved map[string]cachedBinding // outer bindings this scope keeps looking up, see resolve
+	lastMiss string                   // the last name resolved from an outer scope, cached if asked again
+	passed   atomic.Bool              // a cached lookup walked past this scope
+	owns     atomic.Bool              // a cached lookup resolved to a binding in this scope
+}
+
+type cachedBinding struct {
+	binding *Binding
+	owner   *Environment
+	hops    int
+	epoch   uint64
 }
 
 type Binding struct {
@@ -83,6 +102,9 @@ func (e *Environment) ResetForTCO() {
 	defer e.mu.Unlock()
 	e.Bindings = make(map[string]*Binding)
 	e.Defers = nil
+	if e.owns.Load() {
+		bindingEpoch.Add(1)
+	}
 }
 
 func (e *Environment) ShallowCopy() *Environment {
@@ -106,17 +128,68 @@ func (e *Environment) ShallowCopy() *Environment {
 }
 
 func (e *Environment) GetBinding(name string) (*Binding, bool) {
+	binding, _, _ := e.resolve(name, bindingEpoch.Load())
+	return binding, binding != nil
+}
+
+// resolve finds name in this scope or an outer one, returning the scope that holds it
+// and how many scopes were walked to get there. A scope that resolves the same outer
+// name twice caches the result, so loops that reuse a scope skip the walk afterwards.
+func (e *Environment) resolve(name string, epoch uint64) (*Binding, *Environment, int) {
 	e.mu.RLock()
 	binding, ok := e.Bindings[name]
+	cached, hit := e.resolved[name]
 	e.mu.RUnlock()
 
 	if ok {
-		return binding, true
+		return binding, e, 0
 	}
-	if e.Outer != nil {
-		return e.Outer.GetBinding(name)
+	if hit && cached.epoch == epoch {
+		return cached.binding, cached.owner, cached.hops
 	}
-	return nil, false
+	if e.Outer == nil {
+		return nil, nil, 0
+	}
+
+	binding, owner, hops := e.Outer.resolve(name, epoch)
+	if binding == nil {
+		return nil, nil, 0
+	}
+	hops++
+	if hops >= minCachedHops {
+		e.remember(name, binding, owner, hops, epoch)
+	}
+	return binding, owner, hops
+}
+
+func (e *Environment) remember(name string, binding *Binding, owner *Environment, hops int, epoch uint64) {
+	e.mu.Lock()
+	repeat := e.lastMiss == name
+	e.lastMiss = name
+	e.mu.Unlock()
+	if !repeat {
+		return
+	}
+
+	// Mark the path before re-checking it: a scope that gains the name after being
+	// marked advances the epoch, one that gained it before shows up here.
+	for env := e.Outer; env != owner; env = env.Outer {
+		env.passed.Store(true)
+		if _, ok := env.GetLocalBinding(name); ok {
+			return
+		}
+	}
+	owner.owns.Store(true)
+	if current, ok := owner.GetLocalBinding(name); !ok || current != binding {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.resolved == nil {
+		e.resolved = make(map[string]cachedBinding)
+	}
+	e.resolved[name] = cachedBinding{binding: binding, owner: owner, hops: hops, epoch: epoch}
 }
 
 // GetLocalBinding returns a binding from this environment only (it does not walk outers).
@@ -157,6 +230,20 @@ func (e *Environment) DefineConstant(name string, val Object, isExported bool, i
 	return e.define(name, val, false, isExported, isImport)
 }
 
+// DefineForeign binds a host Go function as a constant in this environment so Slug code evaluated
+// in it can call fn by name. Foreign functions declared with `foreign` in a module are resolved
+// when the module loads from the registry in the foreign package; embedders use DefineForeign
+// instead to expose host functions or values at runtime, typically on the root environment before
+// the program is evaluated. The function accepts any number of positional arguments, so fn is
+// responsible for checking them. Errors returned by fn via ctx.NewError are raised as runtime errors.
+func (e *Environment) DefineForeign(name string, fn ForeignFunction) (Object, error) {
+	return e.DefineConstant(name, &Foreign{
+		Name:      name,
+		Fn:        fn,
+		Signature: ast.FSig{Min: 0, Max: math.MaxInt, IsVariadic: true},
+	}, false, false)
+}
+
 // Define adds a new variable with the given name and value to the environment and returns the value
 func (e *Environment) Define(name string, val Object, isExported bool, isImport bool) (Object, error) {
 	return e.define(name, val, true, isExported, isImport)
@@ -238,6 +325,9 @@ func (e *Environment) define(name string, val Object, isMutable bool, isExported
 	}
 
 	e.Bindings[name] = binding
+	if !exists && e.passed.Load() {
+		bindingEpoch.Add(1)
+	}
 
 	var typ ObjectType = "<nil>"
 	if binding.Value != nil {
@@ -373,6 +463,9 @@ func (e *Environment) ExecuteDeferred(result Object, evalFunc func(stmt ast.Stat
 					IsMutable: false,
 					Meta:      Meta{},
 				}
+				if e.passed.Load() || e.owns.Load() {
+					bindingEpoch.Add(1)
+				}
 			}
 
 			// 3. Execute the deferred block
@@ -41,3 +41,45 @@ func formatRuntimeErrorStack(rtErr *RuntimeError) string {
 
 	return buf.String()
 }
+
+// DeepCopy returns a copy of o that shares no mutable storage with the original. Lists, bytes,
+// maps and struct values are copied recursively; everything else is immutable and returned as is.
+func DeepCopy(o Object) Object {
+	switch v := o.(type) {
+	case *List:
+		elements := make([]Object, len(v.Elements))
+		for i, el := range v.Elements {
+			elements[i] = DeepCopy(el)
+		}
+		return &List{Tags: copyTags(v.Tags), Elements: elements}
+	case *Bytes:
+		value := make([]byte, len(v.Value))
+		copy(value, v.Value)
+		return &Bytes{Tags: copyTags(v.Tags), Value: value}
+	case *Map:
+		m := &Map{Tags: copyTags(v.Tags), Pairs: make(map[MapKey]MapPair, len(v.Pairs))}
+		for _, pair := range v.OrderedPairs() {
+			m.Put(pair.Key.(Hashable), DeepCopy(pair.Value))
+		}
+		return m
+	case *StructValue:
+		fields := make(map[string]Object, len(v.Fields))
+		for name, value := range v.Fields {
+			fields[name] = DeepCopy(value)
+		}
+		return &StructValue{Schema: v.Schema, Fields: fields}
+	default:
+		return o
+	}
+}
+
+func copyTags(tags map[string]List) map[string]List {
+	if tags == nil {
+		return nil
+	}
+	c := make(map[string]List, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
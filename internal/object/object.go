@@ -2,15 +2,20 @@ package object
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash/fnv"
 	"log/slog"
 	"math"
+	"regexp"
+	"slices"
 	"slug/internal/ast"
 	"slug/internal/dec64"
 	"slug/internal/util"
+	"sort"
 	"strings"
 	"unicode/utf8"
 )
@@ -29,6 +34,12 @@ const (
 	STRUCT_OBJ        = "STRUCT"
 	CHANNEL_OBJ       = "CHANNEL"
 
+	STRING_BUILDER_OBJ = "STRING_BUILDER"
+	ARRAY_OBJ          = "ARRAY"
+	DICT_OBJ           = "DICT"
+	SET_OBJ            = "SET"
+	COUNTER_OBJ        = "COUNTER"
+
 	MODULE_OBJ         = "MODULE"
 	FUNCTION_OBJ       = "FUNCTION"
 	FUNCTION_GROUP_OBJ = "FUNCTION_GROUP"
@@ -43,9 +54,14 @@ const (
 )
 
 const (
-	IMPORT_TAG   = "@import"
-	EXPORT_TAG   = "@export"
-	FUNCTION_TAG = "@fn"
+	IMPORT_TAG     = "@import"
+	EXPORT_TAG     = "@export"
+	FUNCTION_TAG   = "@fn"
+	DEPRECATED_TAG = "@deprecated"
+	MEMOIZE_TAG    = "@memoize"
+	OPERATOR_TAG   = "@operator"
+	TEST_TAG       = "@test"
+	NONNIL_TAG     = "@nonnil"
 )
 
 var TypeTags = map[string]string{
@@ -71,6 +87,9 @@ var (
 // allowing Foreign Function Interface (FFI) implementations to access the current
 // execution context and helper methods.
 type EvaluatorContext interface {
+	// Context is cancelled along with the calling task. Foreign functions that
+	// block should watch its Done channel and return early.
+	Context() context.Context
 	CurrentEnv() *Environment
 	ApplyFunction(pos int, fnName string, fnObj Object, positional []Object, named map[string]Object) Object
 	NewError(message string, a ...interface{}) *Error
@@ -79,6 +98,9 @@ type EvaluatorContext interface {
 	LoadModule(pathParts string) (*Module, error)
 	GetConfiguration() util.Configuration
 	NextHandleID() int64
+	RandomBytes(p []byte)
+	CompileRegex(pattern string) (*regexp.Regexp, error)
+	ObjectsEqual(a, b Object) bool
 }
 
 type ForeignFunction func(ctx EvaluatorContext, args ...Object) Object
@@ -286,6 +308,7 @@ type Function struct {
 	Body        *ast.BlockStatement
 	Env         *Environment
 	HasTailCall bool
+	Compiled    any // the runtime's pre-resolved form of Body, nil when it evaluates Body directly
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -354,6 +377,45 @@ func (fg *FunctionGroup) allGroups() []*FunctionGroup {
 	return groups
 }
 
+// SameFunction reports whether a and b are the same function value. Functions
+// compare by identity, a Function or Foreign only equals itself however alike
+// another one's source is. Binding a function copies the group holding it, so
+// groups are the same when they hold the same implementations under the same
+// signatures and delegate to the same groups.
+func SameFunction(a, b Object) bool {
+	fa, da, ok := functionParts(a)
+	if !ok {
+		return false
+	}
+	fb, db, ok := functionParts(b)
+	if !ok || len(fa) != len(fb) || len(da) != len(db) {
+		return false
+	}
+	for sig, fn := range fa {
+		if fb[sig] != fn {
+			return false
+		}
+	}
+	for i := range da {
+		if da[i] != db[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func functionParts(obj Object) (map[ast.FSig]Object, []*FunctionGroup, bool) {
+	switch f := obj.(type) {
+	case *Function:
+		return map[ast.FSig]Object{f.Signature: f}, nil, true
+	case *Foreign:
+		return map[ast.FSig]Object{f.Signature: f}, nil, true
+	case *FunctionGroup:
+		return f.Functions, f.Delegates, true
+	}
+	return nil, nil, false
+}
+
 func (fg *FunctionGroup) HasTag(tag string) bool {
 	for _, g := range fg.allGroups() {
 		for _, function := range g.Functions {
@@ -536,6 +598,10 @@ func (fg *FunctionGroup) DispatchToFunction(fnName string, positional []Object,
 					}
 					score = evaluateFunctionMatch(f.Parameters, bound)
 				case *Foreign:
+					if f.RawArgs {
+						// takes whatever it is given, so only the count has to fit
+						break
+					}
 					bound, err := bindArgumentsForDispatch(f.Parameters, positional, named)
 					if err != nil {
 						if firstBindErr == nil {
@@ -624,6 +690,10 @@ type Foreign struct {
 	ParamIndex map[string]int
 	Fn         ForeignFunction
 	Name       string
+	// RawArgs skips parameter binding and variadic expansion. Fn is called with
+	// two arguments, a List of the positional arguments and a Map of the named
+	// ones keyed by symbol, exactly as the caller passed them.
+	RawArgs bool
 }
 
 func (f *Foreign) Type() ObjectType { return FOREIGN_OBJ }
@@ -663,6 +733,24 @@ func (l *List) Inspect() string {
 
 	return out.String()
 }
+
+// MapKey hashes the elements in order so equal lists share a key. Numbers hash by
+// their full value, since Number.MapKey drops the fraction. An element that is not
+// hashable adds only its type, use HashableKey to reject such lists as keys.
+func (l *List) MapKey() MapKey {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, el := range l.Elements {
+		h.Write([]byte(el.Type()))
+		if n, ok := el.(*Number); ok {
+			h.Write([]byte(n.Value.String()))
+		} else if eh, ok := el.(Hashable); ok {
+			binary.LittleEndian.PutUint64(buf[:], eh.MapKey().Value)
+			h.Write(buf[:])
+		}
+	}
+	return MapKey{Type: l.Type(), Value: h.Sum64()}
+}
 func (l *List) HasTag(tag string) bool {
 	return hasTag(tag, l.Tags)
 }
@@ -681,6 +769,22 @@ type MapKey struct {
 	Value uint64
 }
 
+// HashableKey reports whether obj can key a map, lists only when every element can.
+func HashableKey(obj Object) (Hashable, bool) {
+	h, ok := obj.(Hashable)
+	if !ok {
+		return nil, false
+	}
+	if l, isList := obj.(*List); isList {
+		for _, el := range l.Elements {
+			if _, ok := HashableKey(el); !ok {
+				return nil, false
+			}
+		}
+	}
+	return h, true
+}
+
 type MapPair struct {
 	Key   Object
 	Value Object
@@ -689,6 +793,7 @@ type MapPair struct {
 type Map struct {
 	Tags  map[string]List
 	Pairs map[MapKey]MapPair
+	order []MapKey // insertion order of Pairs, maintained by Put, PutPair and Delete
 }
 
 func (m *Map) Type() ObjectType { return MAP_OBJ }
@@ -702,7 +807,7 @@ func (m *Map) Inspect() string {
 	}
 
 	pairs := []string{}
-	for _, pair := range m.Pairs {
+	for _, pair := range m.OrderedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -717,26 +822,82 @@ func (m *Map) Inspect() string {
 
 // Put simplify adding objects to a map
 func (m *Map) Put(k Hashable, v Object) *Map {
-	if m.Pairs == nil {
-		m.Pairs = map[MapKey]MapPair{}
-	}
-	m.Pairs[k.MapKey()] = MapPair{
+	return m.PutPair(k.MapKey(), MapPair{
 		Key:   k,
 		Value: v,
-	}
-	return m
+	})
 }
 func (m *Map) PutPair(k MapKey, v MapPair) *Map {
 	if m.Pairs == nil {
 		m.Pairs = map[MapKey]MapPair{}
 	}
+	if _, ok := m.Pairs[k]; !ok {
+		m.order = append(m.order, k)
+	}
 	m.Pairs[k] = v
 	return m
 }
+func (m *Map) Delete(k MapKey) *Map {
+	if _, ok := m.Pairs[k]; !ok {
+		return m
+	}
+	delete(m.Pairs, k)
+	for i, key := range m.order {
+		if key == k {
+			m.order = append(m.order[:i:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return m
+}
 func (m *Map) Get(k Hashable) (Object, bool) {
 	pair, ok := m.Pairs[k.MapKey()]
 	return pair.Value, ok
 }
+
+// Copy returns a shallow copy of the map that keeps its insertion order.
+func (m *Map) Copy() *Map {
+	c := &Map{Pairs: make(map[MapKey]MapPair, len(m.Pairs))}
+	for _, pair := range m.OrderedPairs() {
+		c.Put(pair.Key.(Hashable), pair.Value)
+	}
+	return c
+}
+
+// OrderedPairs returns the pairs of the map in insertion order. Pairs written to the Pairs map
+// directly, bypassing Put, are not tracked and follow in key order so output stays deterministic.
+func (m *Map) OrderedPairs() []MapPair {
+	pairs := make([]MapPair, 0, len(m.Pairs))
+	for _, k := range m.order {
+		if pair, ok := m.Pairs[k]; ok {
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(pairs) == len(m.Pairs) {
+		return pairs
+	}
+
+	tracked := make(map[MapKey]bool, len(m.order))
+	for _, k := range m.order {
+		tracked[k] = true
+	}
+	untracked := make([]MapKey, 0, len(m.Pairs)-len(pairs))
+	for k := range m.Pairs {
+		if !tracked[k] {
+			untracked = append(untracked, k)
+		}
+	}
+	sort.Slice(untracked, func(i, j int) bool {
+		if untracked[i].Type != untracked[j].Type {
+			return untracked[i].Type < untracked[j].Type
+		}
+		return untracked[i].Value < untracked[j].Value
+	})
+	for _, k := range untracked {
+		pairs = append(pairs, m.Pairs[k])
+	}
+	return pairs
+}
 func (m *Map) HasTag(tag string) bool {
 	return hasTag(tag, m.Tags)
 }
@@ -753,7 +914,12 @@ func (m *Map) SetTag(tag string, params List) {
 type StructSchemaField struct {
 	Name    string
 	Default ast.Expression
-	Hint    string
+	Hint    string   // type tag the value must match, empty when unchecked
+	Tags    []string // other field tags, e.g. @nonnil
+}
+
+func (f StructSchemaField) HasTag(tag string) bool {
+	return slices.Contains(f.Tags, tag)
 }
 
 type StructSchema struct {
@@ -761,6 +927,7 @@ type StructSchema struct {
 	Fields     []StructSchemaField
 	FieldIndex map[string]int
 	Env        *Environment
+	Anonymous  bool // inferred from a #{...} literal, compared by field set
 }
 
 func (s *StructSchema) Type() ObjectType { return STRUCT_SCHEMA_OBJ }
@@ -774,6 +941,10 @@ func (s *StructSchema) Inspect() string {
 	parts := []string{}
 	for _, field := range s.Fields {
 		var b strings.Builder
+		for _, tag := range field.Tags {
+			b.WriteString(tag)
+			b.WriteString(" ")
+		}
 		if field.Hint != "" {
 			b.WriteString(field.Hint)
 			b.WriteString(" ")
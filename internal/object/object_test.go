@@ -77,3 +77,179 @@ func TestSymbolMapKey(t *testing.T) {
 		t.Errorf("symbols with different names have same map keys")
 	}
 }
+
+func TestListMapKey(t *testing.T) {
+	num := func(n int64) Object { return &Number{Value: dec64.FromInt64(n)} }
+	xy1 := &List{Elements: []Object{num(1), num(2)}}
+	xy2 := &List{Elements: []Object{num(1), num(2)}}
+	yx := &List{Elements: []Object{num(2), num(1)}}
+	nested := &List{Elements: []Object{num(1), &List{Elements: []Object{num(2)}}}}
+
+	if xy1.MapKey() != xy2.MapKey() {
+		t.Errorf("lists with same elements have different map keys")
+	}
+	if xy1.MapKey() == yx.MapKey() {
+		t.Errorf("lists with reordered elements have same map keys")
+	}
+	frac1 := &List{Elements: []Object{&Number{Value: dec64.New(15, -1)}, num(2)}}
+	frac2 := &List{Elements: []Object{&Number{Value: dec64.New(12, -1)}, num(2)}}
+	if frac1.MapKey() == frac2.MapKey() {
+		t.Errorf("lists with different fractional elements have same map keys")
+	}
+	if _, ok := HashableKey(nested); !ok {
+		t.Errorf("nested list of hashable values rejected as a key")
+	}
+
+	withMap := &List{Elements: []Object{num(1), &Map{}}}
+	if _, ok := HashableKey(withMap); ok {
+		t.Errorf("list holding a map accepted as a key")
+	}
+	if _, ok := HashableKey(&Map{}); ok {
+		t.Errorf("map accepted as a key")
+	}
+}
+
+func TestMapInsertionOrder(t *testing.T) {
+	m := &Map{}
+	for _, k := range []string{"c", "a", "b"} {
+		m.Put(&String{Value: k}, TRUE)
+	}
+	m.Put(&String{Value: "c"}, FALSE)
+	m.Delete((&String{Value: "a"}).MapKey())
+	m.Put(&String{Value: "a"}, TRUE)
+
+	want := "{c: false, b: true, a: true}"
+	if got := m.Inspect(); got != want {
+		t.Errorf("map inspect order wrong. got=%s, want=%s", got, want)
+	}
+
+	c := m.Copy().Put(&String{Value: "d"}, TRUE)
+	if len(m.OrderedPairs()) != 3 || len(c.OrderedPairs()) != 4 {
+		t.Errorf("copy shares storage with the original map")
+	}
+}
+
+func TestDeepCopyDoesNotAlias(t *testing.T) {
+	inner := &List{Elements: []Object{TRUE}}
+	original := &List{Elements: []Object{inner, &Bytes{Value: []byte{1}}}}
+
+	c := DeepCopy(original).(*List)
+	c.Elements[0].(*List).Elements[0] = FALSE
+	c.Elements[1].(*Bytes).Value[0] = 2
+	c.Elements = append(c.Elements, NIL)
+
+	if inner.Elements[0] != TRUE {
+		t.Errorf("nested list was mutated through the copy")
+	}
+	if original.Elements[1].(*Bytes).Value[0] != 1 {
+		t.Errorf("bytes were mutated through the copy")
+	}
+	if len(original.Elements) != 2 {
+		t.Errorf("original list length changed, got=%d", len(original.Elements))
+	}
+
+	m := (&Map{}).Put(InternSymbol("k"), inner)
+	mc := DeepCopy(m).(*Map)
+	v, _ := mc.Get(InternSymbol("k"))
+	if v == inner {
+		t.Errorf("map values were not copied")
+	}
+}
+
+func TestSameFunction(t *testing.T) {
+	f := &Function{}
+	g := &Function{}
+
+	env := NewRootEnvironment(4)
+	env.Define("f", f, false, false)
+	bound, _ := env.Get("f")
+	env.Define("alias", bound, false, false)
+	alias, _ := env.Get("alias")
+	env.Define("g", g, false, false)
+	other, _ := env.Get("g")
+
+	if !SameFunction(f, f) || !SameFunction(bound, f) || !SameFunction(bound, alias) {
+		t.Errorf("a function should be the same as itself and every binding of it")
+	}
+	if SameFunction(f, g) || SameFunction(bound, other) {
+		t.Errorf("separately defined functions should not be the same")
+	}
+	if SameFunction(f, NIL) || SameFunction(NIL, NIL) {
+		t.Errorf("only functions should be compared as functions")
+	}
+	if _, ok := HashableKey(bound); ok {
+		t.Errorf("functions should not be usable as map keys")
+	}
+}
+
+func nestedEnvironments(depth int) []*Environment {
+	envs := []*Environment{NewRootEnvironment(4)}
+	for i := 1; i < depth; i++ {
+		envs = append(envs, NewEnclosedEnvironment(envs[i-1], nil))
+	}
+	return envs
+}
+
+func lookupNumber(t *testing.T, env *Environment, name string) string {
+	t.Helper()
+	val, ok := env.Get(name)
+	if !ok {
+		t.Fatalf("%s is not defined", name)
+	}
+	return val.Inspect()
+}
+
+func TestCachedLookupsRespectShadowing(t *testing.T) {
+	envs := nestedEnvironments(6)
+	root, leaf := envs[0], envs[5]
+	root.Define("x", &Number{Value: dec64.FromInt(1)}, false, false)
+
+	// the second lookup from leaf is served from its cache
+	for i := 0; i < 3; i++ {
+		if got := lookupNumber(t, leaf, "x"); got != "1" {
+			t.Fatalf("lookup %d: expected 1, got %s", i, got)
+		}
+	}
+
+	// a value changed at the defining scope is seen through the cache
+	root.Assign("x", &Number{Value: dec64.FromInt(2)})
+	if got := lookupNumber(t, leaf, "x"); got != "2" {
+		t.Fatalf("expected assigned value 2, got %s", got)
+	}
+
+	// a new binding on the walked path shadows the cached one
+	envs[3].Define("x", &Number{Value: dec64.FromInt(3)}, false, false)
+	for i := 0; i < 3; i++ {
+		if got := lookupNumber(t, leaf, "x"); got != "3" {
+			t.Fatalf("lookup %d after shadowing: expected 3, got %s", i, got)
+		}
+	}
+
+	// clearing the shadowing scope, as a tail call does, uncovers the outer binding again
+	envs[3].ResetForTCO()
+	if got := lookupNumber(t, leaf, "x"); got != "2" {
+		t.Fatalf("expected outer value 2 after reset, got %s", got)
+	}
+
+	// a scope's own bindings always win over its cache
+	leaf.Define("x", &Number{Value: dec64.FromInt(4)}, false, false)
+	if got := lookupNumber(t, leaf, "x"); got != "4" {
+		t.Fatalf("expected local value 4, got %s", got)
+	}
+	if got := lookupNumber(t, envs[4], "x"); got != "2" {
+		t.Fatalf("sibling path: expected 2, got %s", got)
+	}
+}
+
+func BenchmarkDeepLookup(b *testing.B) {
+	envs := nestedEnvironments(32)
+	envs[0].Define("x", &Number{Value: dec64.FromInt(1)}, false, false)
+	leaf := envs[len(envs)-1]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := leaf.Get("x"); !ok {
+			b.Fatal("x is not defined")
+		}
+	}
+}
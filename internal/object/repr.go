@@ -0,0 +1,114 @@
+package object
+
+import (
+	"fmt"
+	"slug/internal/token"
+	"strings"
+)
+
+// Repr renders obj as Slug source that evaluates back to an equal value. Nil,
+// booleans, numbers, strings, bytes, symbols, and lists and maps of those round
+// trip. Anything else, such as a function or a task, renders as an opaque marker
+// like <function> that is not valid source.
+func Repr(obj Object) string {
+	var out strings.Builder
+	writeRepr(&out, obj)
+	return out.String()
+}
+
+func writeRepr(out *strings.Builder, obj Object) {
+	switch o := obj.(type) {
+	case *Nil:
+		out.WriteString("nil")
+	case *Boolean:
+		out.WriteString(o.Inspect())
+	case *Number:
+		if o.Value.IsNaN() {
+			out.WriteString("<NaN>")
+			return
+		}
+		// Value.String keeps every digit, unlike Inspect under a display precision
+		out.WriteString(o.Value.String())
+	case *String:
+		writeQuoted(out, o.Value)
+	case *Bytes:
+		out.WriteString(o.Inspect())
+	case *Symbol:
+		out.WriteString(":")
+		if isSymbolIdent(o.Name) {
+			out.WriteString(o.Name)
+		} else {
+			writeQuoted(out, o.Name)
+		}
+	case *List:
+		out.WriteString("[")
+		for i, el := range o.Elements {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeRepr(out, el)
+		}
+		out.WriteString("]")
+	case *Map:
+		out.WriteString("{")
+		for i, pair := range o.OrderedPairs() {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeReprKey(out, pair.Key)
+			out.WriteString(": ")
+			writeRepr(out, pair.Value)
+		}
+		out.WriteString("}")
+	default:
+		out.WriteString("<" + strings.ToLower(string(obj.Type())) + ">")
+	}
+}
+
+// writeReprKey writes a map key the way a map literal reads it back: a symbol
+// that is a plain name goes bare, a list is wrapped in [ ] so it is not taken for
+// a computed key.
+func writeReprKey(out *strings.Builder, key Object) {
+	switch k := key.(type) {
+	case *Symbol:
+		if isSymbolIdent(k.Name) && token.LookupIdent(k.Name) == token.IDENT {
+			out.WriteString(k.Name)
+			return
+		}
+	case *List:
+		out.WriteString("[")
+		writeRepr(out, k)
+		out.WriteString("]")
+		return
+	}
+	writeRepr(out, key)
+}
+
+// writeQuoted writes s as a double quoted string literal. `{` is escaped so
+// the result never starts an interpolation, control characters use octal escapes.
+func writeQuoted(out *strings.Builder, s string) {
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			out.WriteString(`\\`)
+		case '"':
+			out.WriteString(`\"`)
+		case '{':
+			out.WriteString(`\{`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(out, `\%03o`, r)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+}
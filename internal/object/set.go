@@ -0,0 +1,78 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Set is an immutable collection of distinct hashable values in insertion order.
+// Operations return new sets, so a set can be shared freely like a list or map.
+type Set struct {
+	members Map
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	elements := make([]string, 0, s.Len())
+	for _, el := range s.Elements() {
+		elements = append(elements, el.Inspect())
+	}
+	return fmt.Sprintf("<set [%s]>", strings.Join(elements, ", "))
+}
+
+// NewSet returns a set of the given values, later duplicates are dropped.
+func NewSet(values ...Hashable) *Set {
+	s := &Set{}
+	for _, v := range values {
+		s.members.Put(v, v)
+	}
+	return s
+}
+
+func (s *Set) Len() int { return len(s.members.Pairs) }
+
+func (s *Set) Has(v Hashable) bool {
+	_, ok := s.members.Get(v)
+	return ok
+}
+
+// Elements returns the members of the set in insertion order.
+func (s *Set) Elements() []Object {
+	pairs := s.members.OrderedPairs()
+	elements := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+	return elements
+}
+
+// With returns a copy of the set that also holds v.
+func (s *Set) With(v Hashable) *Set {
+	return &Set{members: *s.members.Copy().Put(v, v)}
+}
+
+func (s *Set) Union(other *Set) *Set {
+	u := &Set{members: *s.members.Copy()}
+	for _, pair := range other.members.OrderedPairs() {
+		u.members.Put(pair.Key.(Hashable), pair.Key)
+	}
+	return u
+}
+
+func (s *Set) Intersect(other *Set) *Set {
+	return s.filter(func(v Hashable) bool { return other.Has(v) })
+}
+
+func (s *Set) Diff(other *Set) *Set {
+	return s.filter(func(v Hashable) bool { return !other.Has(v) })
+}
+
+func (s *Set) filter(keep func(Hashable) bool) *Set {
+	r := &Set{}
+	for _, pair := range s.members.OrderedPairs() {
+		if v := pair.Key.(Hashable); keep(v) {
+			r.members.Put(v, v)
+		}
+	}
+	return r
+}
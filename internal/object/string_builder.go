@@ -0,0 +1,24 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StringBuilder accumulates a string in place so a loop of appends stays linear.
+// It is the one mutable value in Slug and has no locking: a builder must not be
+// shared between tasks.
+type StringBuilder struct {
+	sb strings.Builder
+}
+
+func (b *StringBuilder) Type() ObjectType { return STRING_BUILDER_OBJ }
+func (b *StringBuilder) Inspect() string  { return fmt.Sprintf("<builder %d>", b.sb.Len()) }
+
+func (b *StringBuilder) Append(s string) {
+	b.sb.WriteString(s)
+}
+
+func (b *StringBuilder) String() string {
+	return b.sb.String()
+}
@@ -127,6 +127,18 @@ func WalkAST(node ast.Node) interface{} {
 			"right":    WalkAST(n.Right),
 		}
 
+	case *ast.ComparisonChain:
+		operands := []interface{}{}
+		for _, operand := range n.Operands {
+			operands = append(operands, WalkAST(operand))
+		}
+		return map[string]interface{}{
+			"type":      "ComparisonChain",
+			"token":     n.TokenLiteral(),
+			"operands":  operands,
+			"operators": n.Operators,
+		}
+
 	case *ast.PrefixExpression:
 		return map[string]interface{}{
 			"type":     "PrefixExpression",
@@ -198,8 +210,12 @@ func WalkAST(node ast.Node) interface{} {
 			Value interface{} `json:"value"`
 		}
 		pairs := make([]pair, 0, len(n.Pairs))
-		for k, v := range n.Pairs {
-			pairs = append(pairs, pair{Key: WalkAST(k), Value: WalkAST(v)})
+		for _, k := range n.Keys {
+			if _, ok := k.(*ast.SpreadExpression); ok {
+				pairs = append(pairs, pair{Key: WalkAST(k)})
+				continue
+			}
+			pairs = append(pairs, pair{Key: WalkAST(k), Value: WalkAST(n.Pairs[k])})
 		}
 		return map[string]interface{}{
 			"type":  "MapLiteral",
@@ -212,7 +228,7 @@ func WalkAST(node ast.Node) interface{} {
 		for i, f := range n.Fields {
 			fields[i] = map[string]interface{}{
 				"name":    f.Name,
-				"hint":    f.Hint,
+				"tags":    f.Tags,
 				"default": WalkAST(f.Default),
 			}
 		}
@@ -254,10 +270,11 @@ func WalkAST(node ast.Node) interface{} {
 
 	case *ast.IndexExpression:
 		return map[string]interface{}{
-			"type":  "IndexExpression",
-			"token": safeTokenLiteral(n),
-			"left":  WalkAST(n.Left),
-			"index": WalkAST(n.Index),
+			"type":     "IndexExpression",
+			"token":    safeTokenLiteral(n),
+			"left":     WalkAST(n.Left),
+			"index":    WalkAST(n.Index),
+			"optional": n.Optional,
 		}
 
 	case *ast.SliceExpression:
@@ -303,6 +320,8 @@ func WalkAST(node ast.Node) interface{} {
 		return map[string]interface{}{"type": "IdentifierPattern", "identifier": WalkAST(n.Value)}
 	case *ast.PinnedIdentifierPattern:
 		return map[string]interface{}{"type": "PinnedIdentifierPattern", "identifier": WalkAST(n.Value)}
+	case *ast.StringConcatPattern:
+		return map[string]interface{}{"type": "StringConcatPattern", "prefix": WalkAST(n.Prefix), "rest": WalkAST(n.Rest), "suffix": WalkAST(n.Suffix)}
 	case *ast.SpreadPattern:
 		return map[string]interface{}{"type": "SpreadPattern", "token": safeTokenLiteral(n), "identifier": WalkAST(n.Value)}
 	case *ast.ListPattern:
@@ -341,6 +360,7 @@ func WalkAST(node ast.Node) interface{} {
 			"token":  n.TokenLiteral(),
 			"schema": WalkAST(n.Schema),
 			"fields": fields,
+			"spread": WalkAST(n.Spread),
 		}
 
 	case *ast.FunctionParameter:
@@ -96,6 +96,13 @@ func RenderASTAsText(node ast.Node, indent int) string {
 	case *ast.InfixExpression:
 		return fmt.Sprintf("(%s %s %s)", RenderASTAsText(n.Left, 0), n.Operator, RenderASTAsText(n.Right, 0))
 
+	case *ast.ComparisonChain:
+		res := RenderASTAsText(n.Operands[0], 0)
+		for i, operand := range n.Operands[1:] {
+			res += fmt.Sprintf(" %s %s", n.Operators[i], RenderASTAsText(operand, 0))
+		}
+		return "(" + res + ")"
+
 	case *ast.PrefixExpression:
 		return fmt.Sprintf("(%s%s)", n.Operator, RenderASTAsText(n.Right, 0))
 
@@ -127,6 +134,9 @@ func RenderASTAsText(node ast.Node, indent int) string {
 		return fmt.Sprintf("%s%s%s => %s", sp, RenderASTAsText(n.Pattern, 0), guard, RenderASTAsText(n.Body, indent))
 
 	case *ast.IndexExpression:
+		if n.Optional {
+			return fmt.Sprintf("%s?[%s]", RenderASTAsText(n.Left, 0), RenderASTAsText(n.Index, 0))
+		}
 		return fmt.Sprintf("%s[%s]", RenderASTAsText(n.Left, 0), RenderASTAsText(n.Index, 0))
 
 	case *ast.SliceExpression:
@@ -168,8 +178,12 @@ func RenderASTAsText(node ast.Node, indent int) string {
 
 	case *ast.MapLiteral:
 		pairs := []string{}
-		for k, v := range n.Pairs {
-			pairs = append(pairs, fmt.Sprintf("%s: %s", RenderASTAsText(k, 0), RenderASTAsText(v, 0)))
+		for _, k := range n.Keys {
+			if _, ok := k.(*ast.SpreadExpression); ok {
+				pairs = append(pairs, RenderASTAsText(k, 0))
+				continue
+			}
+			pairs = append(pairs, fmt.Sprintf("%s: %s", RenderASTAsText(k, 0), RenderASTAsText(n.Pairs[k], 0)))
 		}
 		return "{" + strings.Join(pairs, ", ") + "}"
 
@@ -177,8 +191,8 @@ func RenderASTAsText(node ast.Node, indent int) string {
 		fields := []string{}
 		for _, f := range n.Fields {
 			field := ""
-			if f.Hint != "" {
-				field = f.Hint + " "
+			for _, tag := range f.Tags {
+				field += tag + " "
 			}
 			field += f.Name
 			if f.Default != nil {
@@ -193,6 +207,9 @@ func RenderASTAsText(node ast.Node, indent int) string {
 		for _, f := range n.Fields {
 			fields = append(fields, fmt.Sprintf("%s: %s", f.Name, RenderASTAsText(f.Value, 0)))
 		}
+		if n.Schema == nil {
+			return fmt.Sprintf("#{%s}", strings.Join(fields, ", "))
+		}
 		return fmt.Sprintf("%s {%s}", RenderASTAsText(n.Schema, 0), strings.Join(fields, ", "))
 
 	case *ast.StructCopyExpression:
@@ -214,6 +231,16 @@ func RenderASTAsText(node ast.Node, indent int) string {
 		return RenderASTAsText(n.Value, 0)
 	case *ast.PinnedIdentifierPattern:
 		return "^" + RenderASTAsText(n.Value, 0)
+	case *ast.StringConcatPattern:
+		parts := []string{}
+		if n.Prefix != nil {
+			parts = append(parts, RenderASTAsText(n.Prefix, 0))
+		}
+		parts = append(parts, RenderASTAsText(n.Rest, 0))
+		if n.Suffix != nil {
+			parts = append(parts, RenderASTAsText(n.Suffix, 0))
+		}
+		return strings.Join(parts, " + ")
 	case *ast.SpreadPattern:
 		res := "..."
 		if n.Value != nil {
@@ -250,6 +277,9 @@ func RenderASTAsText(node ast.Node, indent int) string {
 		for _, f := range n.Fields {
 			fields = append(fields, fmt.Sprintf("%s: %s", f.Name, RenderASTAsText(f.Pattern, 0)))
 		}
+		if n.Spread != nil {
+			fields = append(fields, RenderASTAsText(n.Spread, 0))
+		}
 		return fmt.Sprintf("%s {%s}", RenderASTAsText(n.Schema, 0), strings.Join(fields, ", "))
 
 	case *ast.DeferStatement:
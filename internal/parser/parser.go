@@ -14,12 +14,13 @@ import (
 )
 
 const (
-	_           int = iota
-	LOWEST          // assignment
-	LOGICAL_OR      // logical or
-	LOGICAL_AND     // logical and
-	EQUALS          // ==
-	COMPARISON      // > or <
+	_            int = iota
+	LOWEST           // assignment
+	NIL_COALESCE     // ??
+	LOGICAL_OR       // logical or
+	LOGICAL_AND      // logical and
+	EQUALS           // ==
+	COMPARISON       // > or <
 	BITWISE_OR
 	BITWISE_XOR
 	BITWISE_AND // bitwise operators
@@ -39,6 +40,7 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQ:              EQUALS,
 	token.LOGICAL_AND:         LOGICAL_AND,
 	token.LOGICAL_OR:          LOGICAL_OR,
+	token.NIL_COALESCE:        NIL_COALESCE,
 	token.BITWISE_AND:         BITWISE_AND,
 	token.BITWISE_OR:          BITWISE_OR,
 	token.BITWISE_XOR:         BITWISE_XOR,
@@ -58,6 +60,7 @@ var precedences = map[token.TokenType]int{
 	token.CALL_CHAIN:          CALL_CHAIN,
 	token.COPY:                CALL,
 	token.PERIOD:              CALL,
+	token.OPTIONAL_CHAIN:      CALL,
 	token.LPAREN:              CALL,
 	token.INTERPOLATION_START: CALL,
 	token.LBRACKET:            INDEX,
@@ -74,6 +77,7 @@ type Parser struct {
 	Path            string
 	src             string // source code here
 	errors          []string
+	errorPositions  map[int]bool // positions already reported, later errors there are cascades
 	pendingTags     []*ast.Tag
 	pendingDoc      string
 	hasPendingDoc   bool
@@ -86,6 +90,7 @@ type Parser struct {
 	curToken   token.Token
 	peekToken  token.Token
 	peek2Token token.Token // NEW: 2nd lookahead
+	lastEnd    int         // src index just past the last non-newline token consumed
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -97,6 +102,7 @@ func New(l lexer.Tokenizer, path, source string) *Parser {
 		Path:            path,
 		src:             source,
 		errors:          []string{},
+		errorPositions:  map[int]bool{},
 		allowStructInit: true,
 	}
 
@@ -118,6 +124,7 @@ func New(l lexer.Tokenizer, path, source string) *Parser {
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseListLiteral)
 	p.registerPrefix(token.LBRACE, p.parseMapLiteral)
+	p.registerPrefix(token.ANON_STRUCT, p.parseAnonStructLiteral)
 	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.SELECT, p.parseSelectExpression)
 	p.registerPrefix(token.VAR, p.parseVarStatement)
@@ -137,25 +144,28 @@ func New(l lexer.Tokenizer, path, source string) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LOGICAL_AND, p.parseInfixExpression)
 	p.registerInfix(token.LOGICAL_OR, p.parseInfixExpression)
+	p.registerInfix(token.NIL_COALESCE, p.parseInfixExpression)
 	p.registerInfix(token.BITWISE_AND, p.parseInfixExpression)
 	p.registerInfix(token.BITWISE_OR, p.parseInfixExpression)
 	p.registerInfix(token.BITWISE_XOR, p.parseInfixExpression)
 	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
 	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
-	p.registerInfix(token.LT, p.parseInfixExpression)
-	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
-	p.registerInfix(token.GT, p.parseInfixExpression)
-	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.LT, p.parseComparisonExpression)
+	p.registerInfix(token.LT_EQ, p.parseComparisonExpression)
+	p.registerInfix(token.GT, p.parseComparisonExpression)
+	p.registerInfix(token.GT_EQ, p.parseComparisonExpression)
 	p.registerInfix(token.APPEND_ITEM, p.parseInfixExpression)
 	p.registerInfix(token.PREPEND_ITEM, p.parseInfixExpression)
 
 	p.registerInfix(token.CALL_CHAIN, p.parseCallChainExpression)
 	p.registerInfix(token.COPY, p.parseStructCopyExpression)
 	p.registerInfix(token.PERIOD, p.parseDotIdentifierToIndexExpression)
+	p.registerInfix(token.OPTIONAL_CHAIN, p.parseDotIdentifierToIndexExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.LBRACE, p.parseStructInitExpression)
 	p.registerInfix(token.INTERPOLATION_START, p.parseInterpolationExpression)
+	p.registerInfix(token.CUSTOM_OPERATOR, p.parseInfixExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -166,6 +176,9 @@ func New(l lexer.Tokenizer, path, source string) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	if p.curToken.Type != token.NEWLINE && p.curToken.End > 0 {
+		p.lastEnd = p.curToken.End
+	}
 	p.curToken = p.peekToken
 	p.peekToken = p.peek2Token
 	p.peek2Token = p.tokenizer.NextToken()
@@ -196,20 +209,13 @@ func (p *Parser) peekError(t token.TokenType) {
 
 // addErrorAt reports an error at the given absolute position in the source.
 func (p *Parser) addErrorAt(pos int, message string, args ...interface{}) {
-	line, col := util.GetLineAndColumn(p.src, pos)
-	m := fmt.Sprintf(message, args...)
-
-	// Build the error message in the new format
-	var errorMsg bytes.Buffer
-
-	errorMsg.WriteString(fmt.Sprintf("\nParseError: %s\n", m))
-	errorMsg.WriteString(fmt.Sprintf("    --> %s:%d:%d\n", p.Path, line, col))
-
-	// Get context lines (2 lines before, the error line, and potentially lines after)
-	lines := util.GetContextLines(p.src, line, col)
-	errorMsg.WriteString(lines)
+	if p.errorPositions[pos] {
+		return
+	}
+	p.errorPositions[pos] = true
 
-	p.errors = append(p.errors, errorMsg.String())
+	m := fmt.Sprintf(message, args...)
+	p.errors = append(p.errors, "\n"+util.FormatDiagnostic(p.src, p.Path, pos, "ParseError: "+m))
 }
 
 // Update `expectPeek` to include line and column context when a peek error happens
@@ -233,7 +239,11 @@ func (p *Parser) ParseProgram() *ast.Program {
 	p.skipStatementSeparators()
 
 	for !p.curTokenIs(token.EOF) && !p.curTokenIs(token.ILLEGAL) {
+		errorCount := len(p.errors)
 		stmt := p.parseStatement()
+		if len(p.errors) > errorCount {
+			p.skipToStatementBoundary()
+		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 			if p.scopeDepth == 0 {
@@ -257,6 +267,34 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// skipToStatementBoundary recovers from a parse error by discarding the rest of the failed
+// statement: it stops on a separator outside any brackets, or just before a token that starts a
+// new declaration, so later statements are still parsed and their own errors reported.
+func (p *Parser) skipToStatementBoundary() {
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.LPAREN, token.LBRACKET, token.LBRACE, token.ANON_STRUCT,
+			token.MATCH_KEYS_EXACT, token.INTERPOLATION_START:
+			depth++
+		case token.RPAREN, token.RBRACKET, token.RBRACE,
+			token.MATCH_KEYS_CLOSE, token.INTERPOLATION_END:
+			if depth > 0 {
+				depth--
+			}
+		case token.NEWLINE, token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+		if depth == 0 && (p.peekTokenIs(token.VAL) || p.peekTokenIs(token.VAR) ||
+			p.peekTokenIs(token.FOREIGN) || p.peekTokenIs(token.EOF)) {
+			return
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) skipStatementSeparators() {
 	for {
 		if p.curTokenIs(token.SEMICOLON) {
@@ -481,6 +519,7 @@ func (p *Parser) peekIsExpressionTerminator(precedence int) bool {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	p.skipLeadingNewlines()
 
+	start := p.curToken.Position
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
@@ -520,10 +559,35 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 		p.nextToken() // advance onto operator (e.g. '/>')
 		leftExp = infix(leftExp)
+		setSpanStart(leftExp, start)
+	}
+}
+
+// setSpanStart records where an expression built by an infix parse fn begins,
+// which is the start of its leftmost operand.
+func setSpanStart(exp ast.Expression, start int) {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		exp.Start = start
+	case *ast.ComparisonChain:
+		exp.Start = start
+	case *ast.CallExpression:
+		exp.Start = start
+	}
+}
+
+// spanEnd is the src index just past the last token consumed, ignoring newlines.
+func (p *Parser) spanEnd() int {
+	if p.curToken.Type != token.NEWLINE && p.curToken.End > 0 {
+		return p.curToken.End
 	}
+	return p.lastEnd
 }
 
 func (p *Parser) peekPrecedence() int {
+	if p.peekToken.Type == token.CUSTOM_OPERATOR {
+		return customOperatorPrecedence(p.peekToken.Literal)
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
@@ -532,6 +596,9 @@ func (p *Parser) peekPrecedence() int {
 }
 
 func (p *Parser) curPrecedence() int {
+	if p.curToken.Type == token.CUSTOM_OPERATOR {
+		return customOperatorPrecedence(p.curToken.Literal)
+	}
 	if p, ok := precedences[p.curToken.Type]; ok {
 		return p
 	}
@@ -539,6 +606,27 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
+// customOperatorPrecedence gives a custom operator the precedence of the
+// built-in operators sharing its first character, so `<>` binds like `<` and
+// `+++` like `+`. Operators starting with `?`, `~` or `$` bind like `*`.
+func customOperatorPrecedence(op string) int {
+	switch op[0] {
+	case '|':
+		return BITWISE_OR
+	case '^':
+		return BITWISE_XOR
+	case '&':
+		return BITWISE_AND
+	case '=', '!':
+		return EQUALS
+	case '<', '>':
+		return COMPARISON
+	case '+', '-':
+		return SUM
+	}
+	return PRODUCT
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
@@ -547,6 +635,11 @@ func (p *Parser) parseIdentifier() ast.Expression {
 		return p.parseAssignmentExpression(ident)
 	}
 
+	if _, ok := compoundAssignOperators[p.peekToken.Type]; ok {
+		p.nextToken()
+		return p.parseCompoundAssignmentExpression(ident)
+	}
+
 	return ident
 }
 
@@ -685,10 +778,44 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	} else {
 		expression.Right = p.parseExpression(precedence)
 	}
+	expression.End = p.spanEnd()
 
 	return expression
 }
 
+// parseComparisonExpression parses `a < b`, and reads `a < b < c` as a chain
+// comparing each adjacent pair instead of comparing `a < b` with c.
+func (p *Parser) parseComparisonExpression(left ast.Expression) ast.Expression {
+	first := p.parseInfixExpression(left).(*ast.InfixExpression)
+	if !isComparisonToken(p.peekToken.Type) {
+		return first
+	}
+
+	chain := &ast.ComparisonChain{
+		Token:     first.Token,
+		Operands:  []ast.Expression{first.Left, first.Right},
+		Operators: []string{first.Operator},
+	}
+	for isComparisonToken(p.peekToken.Type) {
+		p.nextToken()
+		chain.Operators = append(chain.Operators, p.curToken.Literal)
+		p.nextToken()
+		chain.Operands = append(chain.Operands, p.parseExpression(COMPARISON))
+	}
+	chain.End = p.spanEnd()
+
+	return chain
+}
+
+func isComparisonToken(t token.TokenType) bool {
+	switch t {
+	case token.LT, token.LT_EQ, token.GT, token.GT_EQ:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p *Parser) parseAssignmentExpression(left *ast.Identifier) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -696,13 +823,51 @@ func (p *Parser) parseAssignmentExpression(left *ast.Identifier) ast.Expression
 		Left:     left,
 	}
 
+	expression.Start = left.Token.Position
+
 	precedence := p.curPrecedence()
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
+	expression.End = p.spanEnd()
 
 	return expression
 }
 
+// compoundAssignOperators maps `x op= y` tokens to the infix operator they apply.
+var compoundAssignOperators = map[token.TokenType]token.TokenType{
+	token.PLUS_ASSIGN:     token.PLUS,
+	token.MINUS_ASSIGN:    token.MINUS,
+	token.ASTERISK_ASSIGN: token.ASTERISK,
+	token.SLASH_ASSIGN:    token.SLASH,
+	token.PERCENT_ASSIGN:  token.PERCENT,
+}
+
+// parseCompoundAssignmentExpression desugars `x op= y` into `x = x op (y)`.
+func (p *Parser) parseCompoundAssignmentExpression(left *ast.Identifier) ast.Expression {
+	opType := compoundAssignOperators[p.curToken.Type]
+	opToken := token.Token{Type: opType, Literal: string(opType), Position: p.curToken.Position}
+	assignToken := token.Token{Type: token.ASSIGN, Literal: "=", Position: p.curToken.Position}
+
+	p.nextToken()
+	value := &ast.InfixExpression{
+		Token:    opToken,
+		Operator: opToken.Literal,
+		Left:     left,
+		Right:    p.parseExpression(LOWEST),
+		Start:    left.Token.Position,
+		End:      p.spanEnd(),
+	}
+
+	return &ast.InfixExpression{
+		Token:    assignToken,
+		Operator: assignToken.Literal,
+		Left:     left,
+		Right:    value,
+		Start:    value.Start,
+		End:      value.End,
+	}
+}
+
 func (p *Parser) parseNil() ast.Expression {
 	return &ast.Nil{Token: p.curToken}
 }
@@ -732,8 +897,11 @@ func (p *Parser) parseMatchExpression() ast.Expression {
 	p.nextToken()
 	p.skipCaseSeparators() // allow blank lines after '{'
 
+	// Without a value the cases may be conditions, unless one is injected later
+	conditions := match.Value == nil
+
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		matchCase := p.parseMatchCase()
+		matchCase := p.parseMatchCase(conditions)
 		if matchCase != nil {
 			match.Cases = append(match.Cases, matchCase)
 		}
@@ -773,7 +941,7 @@ func (p *Parser) parseSelectExpression() ast.Expression {
 }
 
 func (p *Parser) skipCaseSeparators() {
-	for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) {
+	for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.SEMICOLON) || p.curTokenIs(token.COMMA) {
 		p.nextToken()
 	}
 }
@@ -839,12 +1007,18 @@ func (p *Parser) parseSelectCase() *ast.SelectCase {
 	return selectCase
 }
 
-func (p *Parser) parseMatchCase() *ast.MatchCase {
+// parseMatchCase parses one case of a match. When conditions is set, a case that
+// starts with an expression rather than a pattern, such as `x > 10`, is read as a
+// condition and kept as a literal pattern, which a match without a value
+// evaluates for truthiness.
+func (p *Parser) parseMatchCase(conditions bool) *ast.MatchCase {
 	matchCase := &ast.MatchCase{Token: p.curToken}
 
 	// Parse the pattern
 	var pattern ast.MatchPattern
-	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.AT) {
+	if conditions && p.isConditionCase() {
+		pattern = &ast.LiteralPattern{Token: p.curToken, Value: p.parseExpression(LOWEST)}
+	} else if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.AT) {
 		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		if !p.expectPeek(token.AT) {
 			return nil
@@ -907,8 +1081,8 @@ func (p *Parser) parseMatchCase() *ast.MatchCase {
 			},
 		}
 
-		// case terminator: ; OR NEWLINE OR } (outer loop handles })
-		if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.NEWLINE) {
+		// case terminator: ; OR , OR NEWLINE OR } (outer loop handles })
+		if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.NEWLINE) {
 			p.nextToken()
 		}
 	}
@@ -916,7 +1090,45 @@ func (p *Parser) parseMatchCase() *ast.MatchCase {
 	return matchCase
 }
 
+// isConditionCase reports whether a match case starts with an expression that
+// is not also a pattern: a name followed by anything a pattern cannot continue
+// with, or a token no pattern starts with, such as `!` or `(`.
+func (p *Parser) isConditionCase() bool {
+	switch p.curToken.Type {
+	case token.IDENT:
+		switch p.peekToken.Type {
+		case token.ROCKET, token.IF, token.COMMA, token.AT, token.LBRACE, token.IDENT:
+			return false
+		case token.PLUS:
+			return p.peek2Token.Type != token.STRING
+		}
+		return true
+	case token.UNDERSCORE, token.ELLIPSIS, token.BITWISE_XOR, token.NUMBER, token.STRING,
+		token.TRUE, token.FALSE, token.NIL, token.LBRACKET, token.LBRACE, token.MATCH_KEYS_EXACT:
+		return false
+	}
+	return p.prefixParseFns[p.curToken.Type] != nil
+}
+
+// parseMatchPattern parses a pattern with an optional `as name` suffix, which
+// binds the whole matched value once the pattern itself has matched. `as` is
+// only read this way between a pattern and a name, so it stays usable as an
+// identifier elsewhere.
 func (p *Parser) parseMatchPattern() ast.MatchPattern {
+	pattern := p.parsePrimaryMatchPattern()
+	for pattern != nil && p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "as" && p.peek2Token.Type == token.IDENT {
+		p.nextToken() // consume 'as'
+		p.nextToken()
+		pattern = &ast.BindingPattern{
+			Token:   p.curToken,
+			Name:    &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+			Pattern: pattern,
+		}
+	}
+	return pattern
+}
+
+func (p *Parser) parsePrimaryMatchPattern() ast.MatchPattern {
 
 	switch p.curToken.Type {
 	case token.UNDERSCORE:
@@ -949,11 +1161,20 @@ func (p *Parser) parseMatchPattern() ast.MatchPattern {
 			schema := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 			return p.parseStructPattern(schema)
 		}
+		if p.peekTokenIs(token.PLUS) && p.peek2Token.Type == token.STRING {
+			return p.parseStringConcatPattern()
+		}
 		return &ast.IdentifierPattern{
 			Token: p.curToken,
 			Value: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
 		}
-	case token.NUMBER, token.STRING, token.TRUE, token.FALSE, token.NIL:
+	case token.STRING:
+		if p.peekTokenIs(token.PLUS) && (p.peek2Token.Type == token.IDENT || p.peek2Token.Type == token.UNDERSCORE) {
+			return p.parseStringConcatPattern()
+		}
+		expr := p.parseExpression(LOWEST)
+		return &ast.LiteralPattern{Token: p.curToken, Value: expr}
+	case token.NUMBER, token.TRUE, token.FALSE, token.NIL:
 		// Literal patterns (numbers, strings, booleans, nil)
 		expr := p.parseExpression(LOWEST)
 		return &ast.LiteralPattern{Token: p.curToken, Value: expr}
@@ -972,6 +1193,36 @@ func (p *Parser) parseMatchPattern() ast.MatchPattern {
 	}
 }
 
+// parseStringConcatPattern parses `"prefix" + rest`, `rest + "suffix"` or both,
+// the current token being the prefix string or the rest identifier.
+func (p *Parser) parseStringConcatPattern() ast.MatchPattern {
+	pattern := &ast.StringConcatPattern{Token: p.curToken}
+
+	if p.curTokenIs(token.STRING) {
+		pattern.Prefix = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		p.nextToken() // consume '+'
+		p.nextToken()
+	}
+
+	if p.curTokenIs(token.UNDERSCORE) {
+		pattern.Rest = &ast.WildcardPattern{Token: p.curToken}
+	} else {
+		pattern.Rest = &ast.IdentifierPattern{
+			Token: p.curToken,
+			Value: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+		}
+	}
+
+	if p.peekTokenIs(token.PLUS) {
+		p.nextToken()
+		if !p.expectPeek(token.STRING) {
+			return nil
+		}
+		pattern.Suffix = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	return pattern
+}
+
 func (p *Parser) parseMultiPattern() ast.MatchPattern {
 	// Multi-pattern: comma-separated list of patterns.
 	//
@@ -994,6 +1245,8 @@ func (p *Parser) parseMultiPattern() ast.MatchPattern {
 			return true
 		case *ast.PinnedIdentifierPattern:
 			return true
+		case *ast.StringConcatPattern:
+			return isNonBinding(pt.Rest)
 
 		case *ast.IdentifierPattern:
 			// `x` would bind; disallow in multi-pattern alternatives
@@ -1238,6 +1491,27 @@ func (p *Parser) parseStructPattern(schema *ast.Identifier) ast.MatchPattern {
 			return pattern
 		}
 
+		if p.curTokenIs(token.ELLIPSIS) {
+			if pattern.Spread != nil {
+				p.addErrorAt(p.curToken.Position, "only one spread allowed in struct pattern")
+				return nil
+			}
+			pattern.Spread = p.parseMatchPattern()
+			if pattern.Spread == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.RBRACE) {
+				break
+			}
+			if !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			for p.peekTokenIs(token.NEWLINE) {
+				p.nextToken()
+			}
+			continue
+		}
+
 		if !p.curTokenIs(token.IDENT) {
 			p.addErrorAt(p.curToken.Position, "expected identifier in struct pattern, got %s", p.curToken.Type)
 			return nil
@@ -1328,6 +1602,7 @@ func (p *Parser) parseInterpolationExpression(left ast.Expression) ast.Expressio
 	if !p.expectPeek(token.INTERPOLATION_END) {
 		return nil
 	}
+	expression.End = p.spanEnd()
 
 	if p.peekTokenIs(token.STRING) {
 		p.nextToken()
@@ -1336,6 +1611,7 @@ func (p *Parser) parseInterpolationExpression(left ast.Expression) ast.Expressio
 			Operator: "+",
 			Left:     expression,
 			Right:    p.parseStringLiteral(),
+			End:      p.spanEnd(),
 		}
 	}
 
@@ -1388,7 +1664,23 @@ func (p *Parser) parseIfExpression() ast.Expression {
 		}
 	}
 
-	return expression
+	return foldLiteralIf(expression)
+}
+
+// foldLiteralIf drops the branch an `if` with a boolean literal condition can
+// never take, leaving the taken block (or nil) in place of the whole expression.
+func foldLiteralIf(ie *ast.IfExpression) ast.Expression {
+	cond, ok := ie.Condition.(*ast.Boolean)
+	if !ok {
+		return ie
+	}
+	if cond.Value {
+		return ie.ThenBranch
+	}
+	if ie.ElseBranch != nil {
+		return ie.ElseBranch
+	}
+	return &ast.Nil{Token: token.Token{Type: token.NIL, Literal: "nil", Position: ie.Token.Position, End: ie.Token.End}}
 }
 
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
@@ -1538,6 +1830,17 @@ func (p *Parser) markTailCall(expr ast.Expression) bool {
 		}
 		return thenHasTail || elseHasTail
 
+	case *ast.BlockStatement:
+		// What is left of an `if` with a literal condition; a nursery waits on its children instead.
+		if e.IsNursery {
+			return false
+		}
+		return p.checkTailCallsInBlock(e)
+
+	case *ast.VarExpression, *ast.ValExpression:
+		// `val x = f()` still binds x after f returns, so a block ending in one is not a tail call
+		return false
+
 	case *ast.MatchExpression:
 		// A match expression has tail calls if any of its cases have tail calls
 		hasAnyTailCall := false
@@ -1548,6 +1851,20 @@ func (p *Parser) markTailCall(expr ast.Expression) bool {
 		}
 		return hasAnyTailCall
 
+	case *ast.InfixExpression:
+		// The right operand of `&&`/`||` is the last thing evaluated, so `cond && recur(...)` is tail.
+		// Ordinary calls there are left unmarked: their value must still be coerced to a boolean.
+		if e.Operator != "&&" && e.Operator != "||" {
+			return false
+		}
+		switch right := e.Right.(type) {
+		case *ast.RecurExpression:
+			return true
+		case *ast.InfixExpression:
+			return p.markTailCall(right)
+		}
+		return false
+
 	default:
 		return false
 	}
@@ -1639,21 +1956,25 @@ func (p *Parser) parseCallChainExpression(left ast.Expression) ast.Expression {
 		Token:     p.curToken,
 		Function:  right,
 		Arguments: []ast.Expression{left},
+		End:       p.spanEnd(),
 	}
 }
 
 func (p *Parser) parseDotIdentifierToIndexExpression(left ast.Expression) ast.Expression {
+	// `a?.b` is an index that yields nil instead of erroring when `a` is nil
+	operator := p.curToken
 	if !p.expectPeek(token.IDENT) {
-		p.addErrorAt(p.curToken.Position, "expected identifier after '.', got %s instead", p.peekToken.Type)
+		p.addErrorAt(p.curToken.Position, "expected identifier after '%s', got %s instead", operator.Literal, p.peekToken.Type)
 		return nil
 	}
 
 	mapKey := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	return &ast.IndexExpression{
-		Token: mapKey.Token,
-		Left:  left,
-		Index: &ast.SymbolLiteral{Token: mapKey.Token, Value: mapKey.Value},
+		Token:    mapKey.Token,
+		Left:     left,
+		Index:    &ast.SymbolLiteral{Token: mapKey.Token, Value: mapKey.Value},
+		Optional: operator.Type == token.OPTIONAL_CHAIN,
 	}
 }
 
@@ -1769,9 +2090,41 @@ func (p *Parser) parseSpawnExpression() ast.Expression {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseCallArguments(token.RPAREN)
+
+	// A block on the same line as the closing paren is a trailing lambda, passed
+	// as the last argument: `withLock(m) { ... }`. Where struct init is off, as
+	// in a match value, the brace belongs to the enclosing construct instead.
+	if p.allowStructInit && p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		if n := len(exp.Arguments); n > 0 {
+			if _, ok := exp.Arguments[n-1].(*ast.NamedArgument); ok {
+				p.addErrorAt(p.curToken.Position, "positional arguments must appear before named arguments")
+			}
+		}
+		exp.Arguments = append(exp.Arguments, p.parseTrailingLambda())
+	}
+
+	exp.End = p.spanEnd()
 	return exp
 }
 
+// parseTrailingLambda parses the block after a call as a function that takes
+// no arguments. It has no `fn` token, so one is made up at the brace.
+func (p *Parser) parseTrailingLambda() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: token.Token{
+		Type:     token.FUNCTION,
+		Literal:  "fn",
+		Position: p.curToken.Position,
+		End:      p.curToken.End,
+	}}
+	lit.Signature = p.generateSignature(lit.Parameters)
+	lit.Body = p.parseBlockStatement()
+
+	p.setTailCallFlags(lit)
+	p.validateRecurUsage(lit)
+	return lit
+}
+
 func (p *Parser) parseCallArguments(end token.TokenType) []ast.Expression {
 	var list []ast.Expression
 
@@ -1780,8 +2133,20 @@ func (p *Parser) parseCallArguments(end token.TokenType) []ast.Expression {
 		return list
 	}
 
+	sawNamed := false
+	appendArgument := func() {
+		start := p.curToken.Position
+		arg := p.parseCallArgument()
+		if _, ok := arg.(*ast.NamedArgument); ok {
+			sawNamed = true
+		} else if sawNamed {
+			p.addErrorAt(start, "positional arguments must appear before named arguments")
+		}
+		list = append(list, arg)
+	}
+
 	p.nextToken()
-	list = append(list, p.parseCallArgument())
+	appendArgument()
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken() // consume comma
@@ -1793,7 +2158,7 @@ func (p *Parser) parseCallArguments(end token.TokenType) []ast.Expression {
 		}
 
 		p.nextToken() // move to next element
-		list = append(list, p.parseCallArgument())
+		appendArgument()
 	}
 
 	if !p.expectPeek(end) {
@@ -1812,9 +2177,10 @@ func (p *Parser) parseCallArgument() ast.Expression {
 		}
 	}
 
-	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+	// A named argument is written `name = value` or `name: value`.
+	if p.curTokenIs(token.IDENT) && (p.peekTokenIs(token.ASSIGN) || p.peekTokenIs(token.COLON)) {
 		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		p.nextToken() // consume '='
+		p.nextToken() // consume '=' or ':'
 		p.nextToken() // move to value
 		return &ast.NamedArgument{
 			Token: name.Token,
@@ -1993,31 +2359,17 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 			return mapLit
 		}
 
-		readIdent := p.curTokenIs(token.LBRACKET)
-		if readIdent {
-			p.nextToken() // consume '['
-		}
-
-		key := p.parseExpression(LOWEST)
-
-		if readIdent {
-			p.expectPeek(token.RBRACKET)
-		}
-
-		if ident, ok := key.(*ast.Identifier); ok && !readIdent {
-			key = &ast.SymbolLiteral{Token: ident.Token, Value: ident.Value}
-		}
-
-		if !p.expectPeek(token.COLON) {
+		if p.curTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			spread := &ast.SpreadExpression{
+				Token: p.curToken,
+				Value: p.parseExpression(LOWEST),
+			}
+			mapLit.Keys = append(mapLit.Keys, spread)
+		} else if !p.parseMapLiteralPair(mapLit) {
 			return nil
 		}
 
-		p.nextToken()
-		p.skipLeadingNewlines() // NEW: allow value on next line
-		value := p.parseExpression(LOWEST)
-
-		mapLit.Pairs[key] = value
-
 		// If next is '}', we're done (no comma)
 		if p.peekTokenIs(token.RBRACE) {
 			break
@@ -2045,6 +2397,36 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 	return mapLit
 }
 
+// parseMapLiteralPair parses one `key: value` entry of a map literal.
+func (p *Parser) parseMapLiteralPair(mapLit *ast.MapLiteral) bool {
+	readIdent := p.curTokenIs(token.LBRACKET)
+	if readIdent {
+		p.nextToken() // consume '['
+	}
+
+	key := p.parseExpression(LOWEST)
+
+	if readIdent {
+		p.expectPeek(token.RBRACKET)
+	}
+
+	if ident, ok := key.(*ast.Identifier); ok && !readIdent {
+		key = &ast.SymbolLiteral{Token: ident.Token, Value: ident.Value}
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return false
+	}
+
+	p.nextToken()
+	p.skipLeadingNewlines() // NEW: allow value on next line
+	value := p.parseExpression(LOWEST)
+
+	mapLit.Pairs[key] = value
+	mapLit.Keys = append(mapLit.Keys, key)
+	return true
+}
+
 func (p *Parser) parseStructSchemaExpression() ast.Expression {
 	schema := &ast.StructSchemaExpression{Token: p.curToken}
 
@@ -2100,9 +2482,16 @@ func (p *Parser) parseStructSchemaField() *ast.StructField {
 	field := &ast.StructField{Token: p.curToken}
 
 	if p.curTokenIs(token.AT) {
-		tag := p.parseTag()
-		field.Hint = tag.Name
-		if !p.expectPeek(token.IDENT) {
+		for p.curTokenIs(token.AT) {
+			tag := p.parseTag()
+			if tag == nil {
+				return nil
+			}
+			field.Tags = append(field.Tags, tag.Name)
+			p.nextToken()
+		}
+		if !p.curTokenIs(token.IDENT) {
+			p.addErrorAt(p.curToken.Position, "expected identifier for struct field, got %s", p.curToken.Type)
 			return nil
 		}
 		field.Token = p.curToken
@@ -2136,6 +2525,20 @@ func (p *Parser) parseStructInitExpression(left ast.Expression) ast.Expression {
 	}
 }
 
+// parseAnonStructLiteral parses `#{x: 1, y: 2}`, a struct init without a schema.
+func (p *Parser) parseAnonStructLiteral() ast.Expression {
+	startToken := p.curToken
+	fields := p.parseStructInitFields()
+	if fields == nil {
+		return nil
+	}
+
+	return &ast.StructInitExpression{
+		Token:  startToken,
+		Fields: fields,
+	}
+}
+
 func (p *Parser) parseStructCopyExpression(left ast.Expression) ast.Expression {
 	copyExpr := &ast.StructCopyExpression{Token: p.curToken, Source: left}
 	if !p.expectPeek(token.LBRACE) {
@@ -2234,12 +2637,14 @@ func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
 
 func (p *Parser) parseNotImplemented() *ast.ThrowStatement {
 	throw := &ast.ThrowStatement{Token: p.curToken}
+	key := &ast.StringLiteral{Token: p.curToken, Value: "type"}
 	pairs := make(map[ast.Expression]ast.Expression)
-	pairs[&ast.StringLiteral{Token: p.curToken, Value: "type"}] = &ast.StringLiteral{Token: p.curToken, Value: "NotImplementedError"}
+	pairs[key] = &ast.StringLiteral{Token: p.curToken, Value: "NotImplementedError"}
 
 	throw.Value = &ast.MapLiteral{
 		Token: p.curToken,
 		Pairs: pairs,
+		Keys:  []ast.Expression{key},
 	}
 
 	if p.peekTokenIs(token.SEMICOLON) {
@@ -2400,12 +2805,12 @@ func isContinuationToken(t token.TokenType) bool {
 	// binary/infix operators
 	case token.PLUS, token.MINUS, token.ASTERISK, token.SLASH, token.PERCENT,
 		token.EQ, token.NOT_EQ, token.LT, token.LT_EQ, token.GT, token.GT_EQ,
-		token.LOGICAL_AND, token.LOGICAL_OR,
+		token.LOGICAL_AND, token.LOGICAL_OR, token.NIL_COALESCE,
 		token.BITWISE_AND, token.BITWISE_OR,
 		token.SHIFT_LEFT, token.SHIFT_RIGHT,
 		token.APPEND_ITEM, token.PREPEND_ITEM,
 		token.CALL_CHAIN, // '/>'
-		token.PERIOD:
+		token.PERIOD, token.OPTIONAL_CHAIN:
 		return true
 	default:
 		return false
@@ -2418,14 +2823,15 @@ func isRhsRequiredToken(t token.TokenType) bool {
 	// (This is mostly a safety net; your Pratt parse often enforces it naturally.)
 	switch t {
 	case token.ASSIGN,
+		token.PLUS_ASSIGN, token.MINUS_ASSIGN, token.ASTERISK_ASSIGN, token.SLASH_ASSIGN, token.PERCENT_ASSIGN,
 		token.PLUS, token.MINUS, token.ASTERISK, token.SLASH, token.PERCENT,
 		token.EQ, token.NOT_EQ, token.LT, token.LT_EQ, token.GT, token.GT_EQ,
-		token.LOGICAL_AND, token.LOGICAL_OR,
+		token.LOGICAL_AND, token.LOGICAL_OR, token.NIL_COALESCE,
 		token.BITWISE_AND, token.BITWISE_OR, token.BITWISE_XOR,
 		token.SHIFT_LEFT, token.SHIFT_RIGHT,
 		token.APPEND_ITEM, token.PREPEND_ITEM,
 		token.CALL_CHAIN,
-		token.PERIOD,
+		token.PERIOD, token.OPTIONAL_CHAIN,
 		token.COLON,  // if you ever parse "key: value" inside expressions
 		token.ROCKET: // in match arms, if relevant to your parse flow
 		return true
@@ -2455,18 +2861,19 @@ func (p *Parser) newlineTerminates() bool {
 }
 
 // validateRecurUsage ensures that all `recur` expressions inside a function
-// appear only in tail position. Violations are reported as parser errors.
+// appear only in tail position and pass an argument count its signature
+// accepts. Violations are reported as parser errors.
 func (p *Parser) validateRecurUsage(fn *ast.FunctionLiteral) {
 	if fn.Body == nil {
 		return
 	}
 	// The function body as a whole is in tail position.
-	p.validateRecurInBlock(fn.Body, true)
+	p.validateRecurInBlock(fn.Body, true, fn)
 }
 
 // validateRecurInBlock walks a block and validates `recur` usage.
 // `inTail` indicates whether the *result* of this block is in tail position.
-func (p *Parser) validateRecurInBlock(block *ast.BlockStatement, inTail bool) {
+func (p *Parser) validateRecurInBlock(block *ast.BlockStatement, inTail bool, fn *ast.FunctionLiteral) {
 	if block == nil || len(block.Statements) == 0 {
 		return
 	}
@@ -2474,21 +2881,21 @@ func (p *Parser) validateRecurInBlock(block *ast.BlockStatement, inTail bool) {
 	for i, stmt := range block.Statements {
 		// Only the last statement can be in tail position relative to this block.
 		stmtInTail := inTail && (i == len(block.Statements)-1)
-		p.validateRecurInStatement(stmt, stmtInTail)
+		p.validateRecurInStatement(stmt, stmtInTail, fn)
 	}
 }
 
 // validateRecurInStatement validates recur usage in a single statement,
 // propagating tail-position information appropriately.
-func (p *Parser) validateRecurInStatement(stmt ast.Statement, inTail bool) {
+func (p *Parser) validateRecurInStatement(stmt ast.Statement, inTail bool, fn *ast.FunctionLiteral) {
 	switch s := stmt.(type) {
 	case *ast.ReturnStatement:
 		// The returned expression is in tail position.
-		p.validateRecurInExpr(s.ReturnValue, true)
+		p.validateRecurInExpr(s.ReturnValue, true, fn)
 
 	case *ast.ExpressionStatement:
 		// The expression is tail-position only if this statement is.
-		p.validateRecurInExpr(s.Expression, inTail)
+		p.validateRecurInExpr(s.Expression, inTail, fn)
 
 	default:
 		// Other statement types cannot be in tail position (their inner
@@ -2500,7 +2907,7 @@ func (p *Parser) validateRecurInStatement(stmt ast.Statement, inTail bool) {
 
 // validateRecurInExpr walks an expression tree and reports non-tail `recur` usage.
 // `inTail` is true only when this expression as a whole is in tail position.
-func (p *Parser) validateRecurInExpr(expr ast.Expression, inTail bool) {
+func (p *Parser) validateRecurInExpr(expr ast.Expression, inTail bool, fn *ast.FunctionLiteral) {
 	if expr == nil {
 		return
 	}
@@ -2511,22 +2918,29 @@ func (p *Parser) validateRecurInExpr(expr ast.Expression, inTail bool) {
 		if !inTail {
 			p.addErrorAt(e.Token.Position, "'recur' is only allowed in tail position")
 		}
+		p.validateRecurArity(e, fn.Signature)
 		// No need to descend further; `recur` has only arguments which are not expressions themselves here.
 
 	case *ast.IfExpression:
 		// Condition is never tail position.
-		p.validateRecurInExpr(e.Condition, false)
+		p.validateRecurInExpr(e.Condition, false, fn)
 
 		// The result of the then/else block is the result of the if-expression.
-		p.validateRecurInBlock(e.ThenBranch, inTail)
+		p.validateRecurInBlock(e.ThenBranch, inTail, fn)
 		if e.ElseBranch != nil {
-			p.validateRecurInBlock(e.ElseBranch, inTail)
+			p.validateRecurInBlock(e.ElseBranch, inTail, fn)
+		}
+
+	case *ast.BlockStatement:
+		// A branch kept by folding an `if` with a literal condition.
+		if !e.IsNursery {
+			p.validateRecurInBlock(e, inTail, fn)
 		}
 
 	case *ast.MatchExpression:
 		// The matched value is not tail-position.
 		if e.Value != nil {
-			p.validateRecurInExpr(e.Value, false)
+			p.validateRecurInExpr(e.Value, false, fn)
 		}
 
 		// Each case body contributes to the result of the whole match.
@@ -2537,10 +2951,10 @@ func (p *Parser) validateRecurInExpr(expr ast.Expression, inTail bool) {
 			// Pattern and guard are never tail-position.
 			// (Patterns are not expressions; guard is a condition.)
 			if c.Guard != nil {
-				p.validateRecurInExpr(c.Guard, false)
+				p.validateRecurInExpr(c.Guard, false, fn)
 			}
 			if c.Body != nil {
-				p.validateRecurInBlock(c.Body, inTail)
+				p.validateRecurInBlock(c.Body, inTail, fn)
 			}
 		}
 
@@ -2551,69 +2965,111 @@ func (p *Parser) validateRecurInExpr(expr ast.Expression, inTail bool) {
 			}
 			switch c.Kind {
 			case ast.SelectRecv:
-				p.validateRecurInExpr(c.Channel, false)
+				p.validateRecurInExpr(c.Channel, false, fn)
 			case ast.SelectSend:
-				p.validateRecurInExpr(c.Channel, false)
-				p.validateRecurInExpr(c.Value, false)
+				p.validateRecurInExpr(c.Channel, false, fn)
+				p.validateRecurInExpr(c.Value, false, fn)
 			case ast.SelectAfter:
-				p.validateRecurInExpr(c.After, false)
+				p.validateRecurInExpr(c.After, false, fn)
 			case ast.SelectAwait:
-				p.validateRecurInExpr(c.Await, false)
+				p.validateRecurInExpr(c.Await, false, fn)
 			case ast.SelectDefault:
 				// no header expression
 			}
 			if c.Handler != nil {
-				p.validateRecurInExpr(c.Handler, inTail)
+				p.validateRecurInExpr(c.Handler, inTail, fn)
 			}
 		}
 
 	case *ast.CallExpression:
 		// Even if the call itself is in tail position, its callee/args are not.
-		p.validateRecurInExpr(e.Function, false)
+		p.validateRecurInExpr(e.Function, false, fn)
 		for _, arg := range e.Arguments {
-			p.validateRecurInExpr(arg, false)
+			p.validateRecurInExpr(arg, false, fn)
 		}
 
 	case *ast.PrefixExpression:
-		p.validateRecurInExpr(e.Right, false)
+		p.validateRecurInExpr(e.Right, false, fn)
 
 	case *ast.InfixExpression:
-		p.validateRecurInExpr(e.Left, false)
-		p.validateRecurInExpr(e.Right, false)
+		// Only the right operand of a short-circuit operator can be the expression's result.
+		p.validateRecurInExpr(e.Left, false, fn)
+		p.validateRecurInExpr(e.Right, inTail && (e.Operator == "&&" || e.Operator == "||"), fn)
+
+	case *ast.ComparisonChain:
+		for _, operand := range e.Operands {
+			p.validateRecurInExpr(operand, false, fn)
+		}
 
 	case *ast.ListLiteral:
 		for _, el := range e.Elements {
-			p.validateRecurInExpr(el, false)
+			p.validateRecurInExpr(el, false, fn)
 		}
 
 	case *ast.MapLiteral:
-		for k, v := range e.Pairs {
-			p.validateRecurInExpr(k, false)
-			p.validateRecurInExpr(v, false)
+		for _, k := range e.Keys {
+			p.validateRecurInExpr(k, false, fn)
+			if v, ok := e.Pairs[k]; ok {
+				p.validateRecurInExpr(v, false, fn)
+			}
 		}
 
 	case *ast.IndexExpression:
-		p.validateRecurInExpr(e.Left, false)
-		p.validateRecurInExpr(e.Index, false)
+		p.validateRecurInExpr(e.Left, false, fn)
+		p.validateRecurInExpr(e.Index, false, fn)
 
 	case *ast.SliceExpression:
-		p.validateRecurInExpr(e.Start, false)
-		p.validateRecurInExpr(e.End, false)
-		p.validateRecurInExpr(e.Step, false)
+		p.validateRecurInExpr(e.Start, false, fn)
+		p.validateRecurInExpr(e.End, false, fn)
+		p.validateRecurInExpr(e.Step, false, fn)
 
 	case *ast.SpreadExpression:
-		p.validateRecurInExpr(e.Value, false)
+		p.validateRecurInExpr(e.Value, false, fn)
 
 	case *ast.FunctionLiteral:
 		// Nested function literals have their own tail-position semantics.
 		// Don't propagate outer tailness into inner functions.
 		p.validateRecurUsage(e)
 
+	case *ast.VarExpression:
+		// The value still has to be matched and bound once it returns, even as a block's last
+		// statement, so it is never tail position.
+		p.validateRecurInExpr(e.Value, false, fn)
+
+	case *ast.ValExpression:
+		p.validateRecurInExpr(e.Value, false, fn)
+
 	default:
 		// For literals, identifiers, etc., there is nothing to check.
 	}
 }
 
+// validateRecurArity reports a `recur` whose positional argument count the
+// enclosing function cannot accept. Spread and named arguments are only known
+// at runtime, so such calls are left to rebinding.
+func (p *Parser) validateRecurArity(recur *ast.RecurExpression, sig ast.FSig) {
+	for _, arg := range recur.Arguments {
+		switch arg.(type) {
+		case *ast.SpreadExpression, *ast.NamedArgument:
+			return
+		}
+	}
+
+	got := len(recur.Arguments)
+	if got >= sig.Min && got <= sig.Max {
+		return
+	}
+
+	want := fmt.Sprintf("%d to %d", sig.Min, sig.Max)
+	switch {
+	case sig.IsVariadic:
+		want = fmt.Sprintf("at least %d", sig.Min)
+	case sig.Min == sig.Max:
+		want = fmt.Sprintf("%d", sig.Min)
+	}
+	p.addErrorAt(recur.Token.Position, "wrong number of arguments to 'recur'. got=%d, want=%s", got, want)
+}
+
 func (p *Parser) validateStructSchemaUsage(program *ast.Program) {
 	for _, stmt := range program.Statements {
 		p.validateStructSchemaInStatement(stmt)
@@ -2720,6 +3176,13 @@ func (p *Parser) containsStructSchema(expr ast.Expression) bool {
 		return false
 	case *ast.InfixExpression:
 		return p.containsStructSchema(e.Left) || p.containsStructSchema(e.Right)
+	case *ast.ComparisonChain:
+		for _, operand := range e.Operands {
+			if p.containsStructSchema(operand) {
+				return true
+			}
+		}
+		return false
 	case *ast.PrefixExpression:
 		return p.containsStructSchema(e.Right)
 	case *ast.IfExpression:
@@ -2757,8 +3220,8 @@ func (p *Parser) containsStructSchema(expr ast.Expression) bool {
 		}
 		return false
 	case *ast.MapLiteral:
-		for k, v := range e.Pairs {
-			if p.containsStructSchema(k) || p.containsStructSchema(v) {
+		for _, k := range e.Keys {
+			if p.containsStructSchema(k) || p.containsStructSchema(e.Pairs[k]) {
 				return true
 			}
 		}
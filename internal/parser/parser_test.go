@@ -5,6 +5,7 @@ import (
 	"slug/internal/ast"
 	"slug/internal/dec64"
 	"slug/internal/lexer"
+	"strings"
 	"testing"
 )
 
@@ -254,6 +255,109 @@ match x {
 	}
 }
 
+func TestMatchAsBindingParsing(t *testing.T) {
+	input := `
+match x {
+  [a, [b, c] as inner] as pair => pair
+  as => as
+}
+`
+
+	p := New(lexer.New(input), "", input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	matchExpr := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+
+	binding, ok := matchExpr.Cases[0].Pattern.(*ast.BindingPattern)
+	if !ok {
+		t.Fatalf("first case pattern not *ast.BindingPattern. got=%T", matchExpr.Cases[0].Pattern)
+	}
+	if binding.Name.Value != "pair" {
+		t.Fatalf("binding name wrong. got=%s", binding.Name.Value)
+	}
+	list, ok := binding.Pattern.(*ast.ListPattern)
+	if !ok {
+		t.Fatalf("binding pattern wrong. got=%T", binding.Pattern)
+	}
+	inner, ok := list.Elements[1].(*ast.BindingPattern)
+	if !ok || inner.Name.Value != "inner" {
+		t.Fatalf("nested element not bound as inner. got=%s", list.Elements[1])
+	}
+
+	if ident, ok := matchExpr.Cases[1].Pattern.(*ast.IdentifierPattern); !ok || ident.Value.Value != "as" {
+		t.Fatalf("`as` alone should bind a name. got=%T", matchExpr.Cases[1].Pattern)
+	}
+}
+
+func TestMatchConditionCaseParsing(t *testing.T) {
+	input := "match { x > 1 => :a, ready => :b, !done => :c, check(x) => :d, [y] => :e, _ => :f }"
+
+	p := New(lexer.New(input), "", input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	matchExpr := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if len(matchExpr.Cases) != 6 {
+		t.Fatalf("matchExpr.Cases length wrong. got=%d", len(matchExpr.Cases))
+	}
+
+	expected := []string{"LiteralPattern (x > 1)", "IdentifierPattern ready", "LiteralPattern (!done)",
+		"LiteralPattern check(x)", "ListPattern [y]", "WildcardPattern _"}
+	for i, c := range matchExpr.Cases {
+		got := strings.TrimPrefix(fmt.Sprintf("%T", c.Pattern), "*ast.") + " " + c.Pattern.String()
+		if got != expected[i] {
+			t.Errorf("case %d: expected=%q, got=%q", i, expected[i], got)
+		}
+	}
+
+	// A match given its value later keeps reading patterns
+	input = "v /> match { n => n }"
+	p = New(lexer.New(input), "", input)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	matchExpr = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if _, ok := matchExpr.Cases[0].Pattern.(*ast.IdentifierPattern); !ok {
+		t.Fatalf("pipeline match case not *ast.IdentifierPattern. got=%T", matchExpr.Cases[0].Pattern)
+	}
+}
+
+func TestStringConcatPatternParsing(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		prefix   string
+		suffix   string
+		expected string
+	}{
+		{`"GET " + rest`, "GET ", "", "GET  + rest"},
+		{`name + ".slug"`, "", ".slug", "name + .slug"},
+		{`"<" + _ + ">"`, "<", ">", "< + _ + >"},
+	}
+
+	for _, tt := range tests {
+		input := "match x {\n  " + tt.pattern + " => 1\n}"
+		p := New(lexer.New(input), "", input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		matchExpr := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+		pattern, ok := matchExpr.Cases[0].Pattern.(*ast.StringConcatPattern)
+		if !ok {
+			t.Fatalf("%s: pattern not *ast.StringConcatPattern. got=%T", tt.pattern, matchExpr.Cases[0].Pattern)
+		}
+		if (pattern.Prefix != nil && pattern.Prefix.Value != tt.prefix) || (pattern.Prefix == nil && tt.prefix != "") {
+			t.Errorf("%s: wrong prefix. got=%v", tt.pattern, pattern.Prefix)
+		}
+		if (pattern.Suffix != nil && pattern.Suffix.Value != tt.suffix) || (pattern.Suffix == nil && tt.suffix != "") {
+			t.Errorf("%s: wrong suffix. got=%v", tt.pattern, pattern.Suffix)
+		}
+		if pattern.String() != tt.expected {
+			t.Errorf("%s: String() wrong. expected=%q, got=%q", tt.pattern, tt.expected, pattern.String())
+		}
+	}
+}
+
 func TestIntegerLiteralExpression(t *testing.T) {
 	input := "5;"
 
@@ -437,6 +541,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a + b * c + d / e - f",
 			"(((a + (b * c)) + (d / e)) - f)",
 		},
+		{
+			"a + b <> c + d",
+			"((a + b) <> (c + d))",
+		},
+		{
+			"a ** b + c",
+			"((a ** b) + c)",
+		},
+		{
+			"a == b <=> c",
+			"(a == (b <=> c))",
+		},
+		{
+			"a |> b && c",
+			"((a |> b) && c)",
+		},
 		{
 			"3 + 4; -5 * 5",
 			"(3 + 4)((-5) * 5)",
@@ -449,6 +569,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"5 < 4 != 3 > 4",
 			"((5 < 4) != (3 > 4))",
 		},
+		{
+			"a < b < c",
+			"(a < b < c)",
+		},
+		{
+			"a < b + 1 <= c >= d",
+			"(a < (b + 1) <= c >= d)",
+		},
+		{
+			"(a < b) < c",
+			"((a < b) < c)",
+		},
+		{
+			"a < b < c == d > e",
+			"((a < b < c) == (d > e))",
+		},
 		{
 			"3 + 4 * 5 == 3 * 1 + 4 * 5",
 			"((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))",
@@ -792,6 +928,107 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
+func TestNamedArgumentParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		named []string // "name=value" for each argument, "" for positional ones
+	}{
+		{"f(a = 1, b = 2)", []string{"a=1", "b=2"}},
+		{"f(a: 1, b: x + 1)", []string{"a=1", "b=(x + 1)"}},
+		{"f(10, c: 2)", []string{"", "c=2"}},
+		{"f(...xs, c = 2, d: 3)", []string{"", "c=2", "d=3"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l, "", tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+		if len(exp.Arguments) != len(tt.named) {
+			t.Fatalf("%q: wrong number of arguments, want=%d, got=%d", tt.input, len(tt.named), len(exp.Arguments))
+		}
+		for i, want := range tt.named {
+			arg, ok := exp.Arguments[i].(*ast.NamedArgument)
+			got := ""
+			if ok {
+				got = arg.Name.Value + "=" + arg.Value.String()
+			}
+			if got != want {
+				t.Errorf("%q: argument %d wrong, want=%q, got=%q", tt.input, i, want, got)
+			}
+		}
+	}
+
+	for _, input := range []string{
+		"f(a = 1, 2)",
+		"f(a: 1, ...rest)",
+		"f(a: 1) { body() }",
+	} {
+		l := lexer.New(input)
+		p := New(l, "", input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || !strings.Contains(errors[0], "positional arguments must appear before named arguments") {
+			t.Errorf("%q: expected a positional after named error, got %v", input, errors)
+		}
+	}
+}
+
+func TestTrailingLambdaParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedArgs []string
+	}{
+		{"withLock(m) { body() }", []string{"m", "fn() {body()}"}},
+		{"timeIt() { 1 }", []string{"fn() {1}"}},
+		{"f(a, b) {\n  val x = a\n  x + b\n}", []string{"a", "b", "fn() {val x = a;(x + b)}"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l, "", tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: expected 1 statement, got %d", tt.input, len(program.Statements))
+		}
+		exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("%q: expected a call expression, got %T", tt.input, program.Statements[0].(*ast.ExpressionStatement).Expression)
+		}
+		if len(exp.Arguments) != len(tt.expectedArgs) {
+			t.Fatalf("%q: wrong number of arguments, want=%d, got=%d", tt.input, len(tt.expectedArgs), len(exp.Arguments))
+		}
+		for i, arg := range tt.expectedArgs {
+			if exp.Arguments[i].String() != arg {
+				t.Errorf("%q: argument %d wrong, want=%q, got=%q", tt.input, i, arg, exp.Arguments[i].String())
+			}
+		}
+
+		lambda, ok := exp.Arguments[len(exp.Arguments)-1].(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("%q: expected the trailing block to be a function literal, got %T", tt.input, exp.Arguments[len(exp.Arguments)-1])
+		}
+		if len(lambda.Parameters) != 0 || lambda.Signature.Min != 0 || lambda.Signature.Max != 0 {
+			t.Errorf("%q: expected the trailing lambda to take no arguments, got %+v", tt.input, lambda.Signature)
+		}
+	}
+
+	// A match value ends at the brace, which opens the match cases.
+	input := "match f(x) { 1 => a; _ => b }"
+	p := New(lexer.New(input), "", input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	match := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+	if match.Value.String() != "f(x)" || len(match.Cases) != 2 {
+		t.Errorf("expected match on f(x) with 2 cases, got %q with %d cases", match.Value.String(), len(match.Cases))
+	}
+}
+
 func TestCallExpressionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -1304,6 +1541,160 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 	return true
 }
 
+func TestRecurInShortCircuitOperands(t *testing.T) {
+	allowed := []string{
+		"fn(n) { n == 0 || recur(n - 1) }",
+		"fn(n) { n > 0 && recur(n - 1) }",
+		"fn(xs) { len(xs) == 0 || (xs[0] > 0 && recur(xs[1:])) }",
+	}
+	for _, input := range allowed {
+		l := lexer.New(input)
+		p := New(l, "", input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		fn, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok || !fn.HasTailCall {
+			t.Errorf("%q: expected the function to be marked as having a tail call", input)
+		}
+	}
+
+	rejected := []string{
+		"fn(n) { recur(n - 1) && n > 0 }",
+		"fn(n) { recur(n - 1) || n > 0 }",
+		"fn(n) { n + 1 == 2 && recur(n - 1) + 1 }",
+	}
+	for _, input := range rejected {
+		l := lexer.New(input)
+		p := New(l, "", input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || !strings.Contains(errors[0], "'recur' is only allowed in tail position") {
+			t.Errorf("%q: expected a tail position error, got %v", input, errors)
+		}
+	}
+}
+
+func TestTailCallsAfterBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantTail bool
+	}{
+		// bindings before a final call or recur leave it in tail position
+		{"fn(n) { val x = n - 1\n recur(x) }", true},
+		{"fn(n) { var x = n - 1\n next(x) }", true},
+		// a final binding runs after its value is computed, so its call is not tail
+		{"fn(n) { val x = next(n) }", false},
+		{"fn(n) { var x = next(n) }", false},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l, "", tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if fn.HasTailCall != tt.wantTail {
+			t.Errorf("%q: expected HasTailCall=%t, got %t", tt.input, tt.wantTail, fn.HasTailCall)
+		}
+	}
+
+	for _, input := range []string{
+		"fn(n) { val x = recur(n - 1) }",
+		"fn(n) { var x = recur(n - 1)\n x + 1 }",
+	} {
+		l := lexer.New(input)
+		p := New(l, "", input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || !strings.Contains(errors[0], "'recur' is only allowed in tail position") {
+			t.Errorf("%q: expected a tail position error, got %v", input, errors)
+		}
+	}
+}
+
+func TestRecurArity(t *testing.T) {
+	allowed := []string{
+		"fn(n, acc) { recur(n - 1, acc + n) }",
+		"fn(n, acc = 0) { recur(n - 1) }",
+		"fn(n, ...rest) { recur(n - 1, 1, 2, 3) }",
+		"fn(a, b) { recur(...[b, a]) }",
+		"fn(n) { fn(a, b) { recur(a, b) } }",
+	}
+	for _, input := range allowed {
+		l := lexer.New(input)
+		p := New(l, "", input)
+		p.ParseProgram()
+		checkParserErrors(t, p)
+	}
+
+	rejected := []struct {
+		input string
+		want  string
+	}{
+		{"fn(n, acc) { recur(n - 1) }", "got=1, want=2"},
+		{"fn(n) { recur(n - 1, 0) }", "got=2, want=1"},
+		{"fn(n, acc = 0) { recur() }", "got=0, want=1 to 2"},
+		{"fn(a, b, ...rest) { recur(a) }", "got=1, want=at least 2"},
+		{"fn(a, b) { fn(n) { recur(a, b) } }", "got=2, want=1"},
+	}
+	for _, tt := range rejected {
+		l := lexer.New(tt.input)
+		p := New(l, "", tt.input)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || !strings.Contains(errors[0], "wrong number of arguments to 'recur'. "+tt.want) {
+			t.Errorf("%q: expected a recur arity error with %q, got %v", tt.input, tt.want, errors)
+		}
+	}
+}
+
+func TestParserRecoversAfterStatementErrors(t *testing.T) {
+	input := `val f = fn(a b) { a }
+val ok = 1
+val m = {a: 1 b: 2, c: 3}
+println(ok)
+val g = 1 + * 2
+`
+	l := lexer.New(input)
+	p := New(l, "", input)
+	program := p.ParseProgram()
+
+	errors := p.Errors()
+	wants := []string{
+		"--> :1:14",
+		"--> :3:15",
+		"no prefix parse function for '*' found",
+	}
+	if len(errors) != len(wants) {
+		t.Fatalf("expected %d errors, got %d: %v", len(wants), len(errors), errors)
+	}
+	for i, want := range wants {
+		if !strings.Contains(errors[i], want) {
+			t.Errorf("error %d: expected %q in %q", i, want, errors[i])
+		}
+	}
+
+	// statements between the broken ones are still parsed
+	found := false
+	for _, stmt := range program.Statements {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+		if call, ok := es.Expression.(*ast.CallExpression); ok && call.Function.String() == "println" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected println(ok) to be parsed after recovering, got %d statements", len(program.Statements))
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -1316,3 +1707,243 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestCallExpressionSpanCoversArguments(t *testing.T) {
+	input := `add(1,
+	2 * three)
+`
+	l := lexer.New(input)
+	p := New(l, "", input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("expression is not *ast.CallExpression. got=%T", stmt.Expression)
+	}
+
+	spanText := func(n ast.Spanned) string {
+		start, end := n.Span()
+		return input[start:end]
+	}
+
+	if got := spanText(call); got != "add(1,\n\t2 * three)" {
+		t.Errorf("call span wrong. got=%q", got)
+	}
+	if got := spanText(call.Function.(*ast.Identifier)); got != "add" {
+		t.Errorf("callee span wrong. got=%q", got)
+	}
+	if got := spanText(call.Arguments[1].(*ast.InfixExpression)); got != "2 * three" {
+		t.Errorf("argument span wrong. got=%q", got)
+	}
+}
+
+func TestCollectSymbols(t *testing.T) {
+	input := `val max = 10
+var count = 0
+val add = fn(a, b) { a + b }
+foreign now = fn()
+val Point = struct { x, y }
+val [first, ...rest] = [1, 2, 3]
+val {name} = {name: "n"}
+println(add(max, count))
+`
+	l := lexer.New(input)
+	p := New(l, "", input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	want := []Symbol{
+		{Name: "max", Kind: SymbolVal, Position: strings.Index(input, "max")},
+		{Name: "count", Kind: SymbolVar, Position: strings.Index(input, "count")},
+		{Name: "add", Kind: SymbolFn, Position: strings.Index(input, "add")},
+		{Name: "now", Kind: SymbolForeign, Position: strings.Index(input, "now")},
+		{Name: "Point", Kind: SymbolStruct, Position: strings.Index(input, "Point")},
+		{Name: "first", Kind: SymbolVal, Position: strings.Index(input, "first")},
+		{Name: "rest", Kind: SymbolVal, Position: strings.Index(input, "rest")},
+		{Name: "name", Kind: SymbolVal, Position: strings.Index(input, "name")},
+	}
+
+	got := CollectSymbols(program)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d symbols, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("symbol %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestResolverUndefined(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		undefined []string
+	}{
+		{"undefined use", "val a = 1\nprintln(a + b)", []string{"b"}},
+		{"forward reference from a function", "val f = fn() { g() }\nval g = fn() { 1 }\nf()", nil},
+		{"forward reference in a nested block", "val f = fn() {\n  val h = fn() { k }\n  val k = 2\n  h()\n}", nil},
+		{"parameters and defaults", "val f = fn(a, b = a, ...rest) { [a, b, rest, c] }", []string{"c"}},
+		{"match bindings stay in their case", "match [1, 2] {\n  [x, ...xs] if x > 0 => xs\n  _ => x\n}", []string{"x"}},
+		{"pinned and struct pattern names are uses", "val P = struct { v }\nmatch 1 { ^one => 1; Q{v: w} => w }", []string{"one", "Q"}},
+		{"valueless match conditions are uses", "val ready = true\nmatch { ready => 1; done => 2 }", []string{"done"}},
+		{"defer onerror binds the error", "val f = fn() {\n  defer onerror(err) { println(err, other) }\n  1\n}", []string{"other"}},
+		{"listed wildcard import", `var {*} = import("m")` + "\nfrom(1) + missing", []string{"missing"}},
+		{"unknown wildcard import", `var {*} = import("elsewhere")` + "\nanything(1)", nil},
+		{"named imports", `var {from} = import("m")` + "\nfrom(1) + other(2)", []string{"other"}},
+		{"builtins and struct init", "val P = struct { v }\nprintln(P { v: len([]) }, Missing { v: 1 })", []string{"Missing"}},
+		{"assignment target", "var a = 1\na = 2\nz = 3", []string{"z"}},
+		{"wildcard map pattern", "val m = {k: 1}\nmatch m {\n  {*} => k\n}", nil},
+		{"select handler match binds", "val c = 1\nselect {\n  recv c /> match { [v, _] => v; _ => w }\n}", []string{"w"}},
+	}
+
+	resolver := &Resolver{
+		Globals: []string{"println", "len", "import"},
+		ModuleNames: func(module string) ([]string, bool) {
+			if module == "m" {
+				return []string{"from"}, true
+			}
+			return nil, false
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input), "", tt.input)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			var got []string
+			for _, d := range resolver.Undefined(program) {
+				name := strings.TrimPrefix(d.Message, "identifier not found: ")
+				if tt.input[d.Position:d.Position+len(name)] != name {
+					t.Errorf("diagnostic for %s points at %q", name, tt.input[d.Position:])
+				}
+				got = append(got, name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.undefined, ",") {
+				t.Errorf("expected undefined %v, got %v", tt.undefined, got)
+			}
+		})
+	}
+}
+
+func TestResolverUnused(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		unused []string
+	}{
+		{"unused local", "val f = fn() {\n  val a = 1\n  val b = 2\n  b\n}\nf()", []string{"a"}},
+		{"used local", "val f = fn(n) {\n  var total = n\n  total + 1\n}\nf(1)", nil},
+		{"used before its definition", "val f = fn() { g() }\nval g = fn() { 1 }\nf()", nil},
+		{"exported and test bindings", "@export\nval api = 1\n@test\nval check = fn() { 1 }", nil},
+		{"operator bindings", "@operator(\"<>\")\nval concat = fn(a, b) { a + b }\n1 <> 2", nil},
+		{"wildcards and underscore names", "val [_, _rest, last] = [1, 2, 3]\nlast", nil},
+		{"destructured names", "val {a, b} = {a: 1, b: 2}\nb", []string{"a"}},
+		{"shadowed outer binding", "val x = 1\nval f = fn() {\n  val x = 2\n  x\n}\nf()", []string{"x"}},
+	}
+
+	resolver := &Resolver{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input), "", tt.input)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			var got []string
+			for _, d := range resolver.Unused(program) {
+				name := strings.TrimPrefix(d.Message, "unused binding: ")
+				if tt.input[d.Position:d.Position+len(name)] != name {
+					t.Errorf("diagnostic for %s points at %q", name, tt.input[d.Position:])
+				}
+				got = append(got, name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.unused, ",") {
+				t.Errorf("expected unused %v, got %v", tt.unused, got)
+			}
+		})
+	}
+}
+
+func TestResolverUnreachable(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // the first word of each unreachable statement
+	}{
+		{"code after return", "val f = fn() {\n  return 1\n  println(2)\n  3\n}", []string{"println"}},
+		{"return as the last statement", "val f = fn(n) {\n  println(n)\n  return n\n}", nil},
+		{"code after throw", "val f = fn() {\n  throw 1\n  cleanup()\n}", []string{"cleanup"}},
+		{"return in an if branch", "val f = fn(n) {\n  if (n) { return 1 }\n  other(n)\n}", nil},
+		{"code after return in an if branch", "val f = fn(n) {\n  if (n) {\n    return 1\n    dead()\n  }\n  n\n}", []string{"dead"}},
+		{"match arms", "val f = fn(n) {\n  match n {\n    1 => { return 1; armDead() }\n    _ => { return 2 }\n  }\n  afterMatch()\n}", []string{"armDead"}},
+	}
+
+	resolver := &Resolver{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input), "", tt.input)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			var got []string
+			for _, d := range resolver.Unreachable(program) {
+				word := tt.input[d.Position:]
+				if i := strings.IndexAny(word, "( \n"); i >= 0 {
+					word = word[:i]
+				}
+				got = append(got, word)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected unreachable %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIfWithLiteralConditionKeepsTakenBranch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`if (true) { 1 } else { 2 }`, "{1}"},
+		{`if (false) { 1 } else { 2 }`, "{2}"},
+		{`if (false) { 1 }`, "nil"},
+		{`if (false) { 1 } else if (true) { 2 } else { 3 }`, "{{2}}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l, "", tt.input)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.IfExpression); ok {
+			t.Errorf("%q: if expression was not folded", tt.input)
+			continue
+		}
+		if got := stmt.Expression.String(); got != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+
+	input := `if (x) { 1 } else { 2 }`
+	l := lexer.New(input)
+	p := New(l, "", input)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if _, ok := stmt.Expression.(*ast.IfExpression); !ok {
+		t.Fatalf("non-literal condition should stay an if expression, got %T", stmt.Expression)
+	}
+}
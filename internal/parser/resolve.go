@@ -0,0 +1,516 @@
+package parser
+
+import (
+	"slug/internal/ast"
+	"sort"
+	"strings"
+)
+
+// Diagnostic is a problem found in a parsed program without running it.
+type Diagnostic struct {
+	Position int // src index of the offending node
+	Message  string
+}
+
+// Resolver finds identifiers that a program uses but never defines, the
+// errors evalIdentifier would otherwise only report when the use is reached.
+type Resolver struct {
+	// Globals are names defined outside any module, such as the builtins.
+	Globals []string
+	// ModuleNames lists the names a `var {*} = import(module)` brings into
+	// scope. When it is nil or reports false, uses in that scope are not checked.
+	ModuleNames func(module string) ([]string, bool)
+}
+
+// scope holds the names declared in one block, function or match case. An open
+// scope imported names that could not be listed, so any name may be defined.
+// Names bound by val and var are tracked until something uses them.
+type scope struct {
+	names   map[string]bool
+	open    bool
+	outer   *scope
+	tracked map[string]int // val and var names to their src index
+	used    map[string]bool
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		names:   map[string]bool{},
+		outer:   outer,
+		tracked: map[string]int{},
+		used:    map[string]bool{},
+	}
+}
+
+// resolve marks the nearest declaration of name as used and reports whether
+// any enclosing scope defines it.
+func (s *scope) resolve(name string) bool {
+	defined := false
+	for ; s != nil; s = s.outer {
+		if s.names[name] {
+			s.used[name] = true
+			return true
+		}
+		if s.open {
+			defined = true
+		}
+	}
+	return defined
+}
+
+// Undefined reports every use of a name that no enclosing scope declares.
+// Declarations are visible to the whole block they appear in, so function
+// bodies may refer to names defined further down the module.
+func (r *Resolver) Undefined(program *ast.Program) []Diagnostic {
+	return r.resolve(program).undefined
+}
+
+// Unused reports val and var bindings that nothing in their scope reads. The
+// `_` wildcard, names starting with `_` and bindings tagged @export, @test or
+// @operator are left out, as they are either ignored on purpose or used from
+// outside or through an operator.
+func (r *Resolver) Unused(program *ast.Program) []Diagnostic {
+	unused := r.resolve(program).unused
+	sort.Slice(unused, func(i, j int) bool { return unused[i].Position < unused[j].Position })
+	return unused
+}
+
+// Unreachable reports the first statement after a return or throw in the same
+// block. A return nested in an if or a match arm only ends that branch, so the
+// statements after the branch are still reachable.
+func (r *Resolver) Unreachable(program *ast.Program) []Diagnostic {
+	unreachable := r.resolve(program).unreachable
+	sort.Slice(unreachable, func(i, j int) bool { return unreachable[i].Position < unreachable[j].Position })
+	return unreachable
+}
+
+func (r *Resolver) resolve(program *ast.Program) *resolution {
+	res := &resolution{resolver: r}
+	if program == nil {
+		return res
+	}
+
+	globals := newScope(nil)
+	for _, name := range r.Globals {
+		globals.names[name] = true
+	}
+	res.statements(program.Statements, newScope(globals))
+	return res
+}
+
+type resolution struct {
+	resolver    *Resolver
+	undefined   []Diagnostic
+	unused      []Diagnostic
+	unreachable []Diagnostic
+}
+
+func (res *resolution) use(ident *ast.Identifier, s *scope) {
+	if ident != nil && !s.resolve(ident.Value) {
+		res.undefined = append(res.undefined, Diagnostic{
+			Position: ident.Token.Position,
+			Message:  "identifier not found: " + ident.Value,
+		})
+	}
+}
+
+// statements declares everything a block binds before resolving any of it.
+func (res *resolution) statements(stmts []ast.Statement, s *scope) {
+	for _, stmt := range stmts {
+		switch st := stmt.(type) {
+		case *ast.ForeignFunctionDeclaration:
+			if st.Name != nil {
+				s.names[st.Name.Value] = true
+			}
+		case *ast.ExpressionStatement:
+			res.declareBinding(st.Expression, s)
+		}
+	}
+	for _, stmt := range stmts {
+		res.statement(stmt, s)
+	}
+
+	for i, stmt := range stmts[:max(len(stmts)-1, 0)] {
+		var keyword string
+		switch stmt.(type) {
+		case *ast.ReturnStatement:
+			keyword = "return"
+		case *ast.ThrowStatement:
+			keyword = "throw"
+		default:
+			continue
+		}
+		res.unreachable = append(res.unreachable, Diagnostic{
+			Position: statementPosition(stmts[i+1]),
+			Message:  "unreachable code after " + keyword,
+		})
+		break
+	}
+
+	for name, pos := range s.tracked {
+		if !s.used[name] {
+			res.unused = append(res.unused, Diagnostic{
+				Position: pos,
+				Message:  "unused binding: " + name,
+			})
+		}
+	}
+}
+
+func statementPosition(stmt ast.Statement) int {
+	switch st := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return st.Token.Position
+	case *ast.ReturnStatement:
+		return st.Token.Position
+	case *ast.ThrowStatement:
+		return st.Token.Position
+	case *ast.BlockStatement:
+		return st.Token.Position
+	case *ast.ForeignFunctionDeclaration:
+		return st.Token.Position
+	case *ast.DeferStatement:
+		if st != nil {
+			return st.Token.Position
+		}
+	}
+	return 0
+}
+
+func (res *resolution) declareBinding(expr ast.Expression, s *scope) {
+	switch e := expr.(type) {
+	case *ast.VarExpression:
+		res.declarePattern(e.Pattern, e.Value, s)
+		track(e.Pattern, e.Tags, s)
+	case *ast.ValExpression:
+		res.declarePattern(e.Pattern, e.Value, s)
+		track(e.Pattern, e.Tags, s)
+	}
+}
+
+// track records the names a val or var pattern binds so unused ones can be
+// reported once the scope has been resolved.
+func track(pattern ast.MatchPattern, tags []*ast.Tag, s *scope) {
+	for _, tag := range tags {
+		if tag.Name == "@export" || tag.Name == "@test" || tag.Name == "@operator" {
+			return
+		}
+	}
+	for _, sym := range appendPatternSymbols(nil, pattern, "") {
+		if strings.HasPrefix(sym.Name, "_") {
+			continue
+		}
+		if _, ok := s.tracked[sym.Name]; !ok {
+			s.tracked[sym.Name] = sym.Position
+		}
+	}
+}
+
+// declarePattern binds the names in a var or val pattern. A `{*}` pattern binds
+// whatever the imported modules define.
+func (res *resolution) declarePattern(pattern ast.MatchPattern, value ast.Expression, s *scope) {
+	if mp, ok := pattern.(*ast.MapPattern); ok && mp.SelectAll {
+		if names, ok := res.importedNames(value); ok {
+			for _, name := range names {
+				s.names[name] = true
+			}
+			for _, entry := range mp.Pairs {
+				bindPattern(entry.Pattern, s)
+			}
+			bindPattern(mp.Spread, s)
+			return
+		}
+	}
+	bindPattern(pattern, s)
+}
+
+func (res *resolution) importedNames(value ast.Expression) ([]string, bool) {
+	call, ok := value.(*ast.CallExpression)
+	if !ok || res.resolver.ModuleNames == nil {
+		return nil, false
+	}
+	if fn, ok := call.Function.(*ast.Identifier); !ok || fn.Value != "import" {
+		return nil, false
+	}
+
+	var names []string
+	for _, arg := range call.Arguments {
+		lit, ok := arg.(*ast.StringLiteral)
+		if !ok {
+			return nil, false
+		}
+		modNames, ok := res.resolver.ModuleNames(lit.Value)
+		if !ok {
+			return nil, false
+		}
+		names = append(names, modNames...)
+	}
+	return names, true
+}
+
+// bindPattern declares the names a pattern binds. A `{*}` pattern binds every
+// key of a map that is only known at runtime, so it opens the scope.
+func bindPattern(pattern ast.MatchPattern, s *scope) {
+	switch p := pattern.(type) {
+	case *ast.IdentifierPattern:
+		if p.Value != nil {
+			s.names[p.Value.Value] = true
+		}
+	case *ast.BindingPattern:
+		if p.Name != nil {
+			s.names[p.Name.Value] = true
+		}
+		bindPattern(p.Pattern, s)
+	case *ast.StringConcatPattern:
+		bindPattern(p.Rest, s)
+	case *ast.SpreadPattern:
+		if p.Value != nil {
+			s.names[p.Value.Value] = true
+		}
+	case *ast.MultiPattern:
+		for _, sub := range p.Patterns {
+			bindPattern(sub, s)
+		}
+	case *ast.ListPattern:
+		for _, el := range p.Elements {
+			bindPattern(el, s)
+		}
+	case *ast.MapPattern:
+		if p.SelectAll {
+			s.open = true
+		}
+		for _, entry := range p.Pairs {
+			bindPattern(entry.Pattern, s)
+		}
+		bindPattern(p.Spread, s)
+	case *ast.StructPattern:
+		for _, field := range p.Fields {
+			bindPattern(field.Pattern, s)
+		}
+		bindPattern(p.Spread, s)
+	}
+}
+
+// patternUses resolves the parts of a pattern that read existing names rather
+// than bind new ones: pinned names, struct schemas and literal expressions.
+func (res *resolution) patternUses(pattern ast.MatchPattern, s *scope) {
+	switch p := pattern.(type) {
+	case *ast.PinnedIdentifierPattern:
+		res.use(p.Value, s)
+	case *ast.LiteralPattern:
+		res.expr(p.Value, s)
+	case *ast.BindingPattern:
+		res.patternUses(p.Pattern, s)
+	case *ast.MultiPattern:
+		for _, sub := range p.Patterns {
+			res.patternUses(sub, s)
+		}
+	case *ast.ListPattern:
+		for _, el := range p.Elements {
+			res.patternUses(el, s)
+		}
+	case *ast.MapPattern:
+		for _, entry := range p.Pairs {
+			res.expr(entry.Key, s)
+			res.patternUses(entry.Pattern, s)
+		}
+		res.patternUses(p.Spread, s)
+	case *ast.StructPattern:
+		res.use(p.Schema, s)
+		for _, field := range p.Fields {
+			res.patternUses(field.Pattern, s)
+		}
+		res.patternUses(p.Spread, s)
+	}
+}
+
+// conditionUses resolves a case of a match without a value, where patterns are
+// conditions and a bare name is read rather than bound.
+func (res *resolution) conditionUses(pattern ast.MatchPattern, s *scope) {
+	switch p := pattern.(type) {
+	case *ast.IdentifierPattern:
+		res.use(p.Value, s)
+	case *ast.LiteralPattern:
+		res.expr(p.Value, s)
+	case *ast.BindingPattern:
+		res.conditionUses(p.Pattern, s)
+	case *ast.MultiPattern:
+		for _, sub := range p.Patterns {
+			res.conditionUses(sub, s)
+		}
+	}
+}
+
+func (res *resolution) tags(tags []*ast.Tag, s *scope) {
+	for _, tag := range tags {
+		for _, arg := range tag.Args {
+			res.expr(arg, s)
+		}
+	}
+}
+
+func (res *resolution) statement(stmt ast.Statement, s *scope) {
+	switch st := stmt.(type) {
+	case *ast.ExpressionStatement:
+		res.expr(st.Expression, s)
+	case *ast.ReturnStatement:
+		res.expr(st.ReturnValue, s)
+	case *ast.ThrowStatement:
+		res.expr(st.Value, s)
+	case *ast.BlockStatement:
+		res.block(st, s)
+	case *ast.ForeignFunctionDeclaration:
+		res.tags(st.Tags, s)
+		res.parameters(st.Parameters, newScope(s))
+	case *ast.DeferStatement:
+		if st == nil {
+			return
+		}
+		inner := s
+		if st.ErrorName != nil {
+			inner = newScope(s)
+			inner.names[st.ErrorName.Value] = true
+		}
+		res.statement(st.Call, inner)
+	}
+}
+
+func (res *resolution) block(block *ast.BlockStatement, s *scope) {
+	if block == nil {
+		return
+	}
+	res.expr(block.Limit, s)
+	res.statements(block.Statements, newScope(s))
+}
+
+// parameters binds each parameter in s, after resolving its default, which may
+// refer to the parameters before it.
+func (res *resolution) parameters(params []*ast.FunctionParameter, s *scope) {
+	for _, param := range params {
+		res.tags(param.Tags, s)
+		res.expr(param.Default, s)
+		if param.Name != nil {
+			s.names[param.Name.Value] = true
+		}
+	}
+}
+
+func (res *resolution) exprs(exprs []ast.Expression, s *scope) {
+	for _, e := range exprs {
+		res.expr(e, s)
+	}
+}
+
+func (res *resolution) expr(expr ast.Expression, s *scope) {
+	switch e := expr.(type) {
+	case nil:
+	case *ast.Identifier:
+		res.use(e, s)
+	case *ast.VarExpression:
+		res.declareBinding(e, s)
+		res.tags(e.Tags, s)
+		res.patternUses(e.Pattern, s)
+		res.expr(e.Value, s)
+	case *ast.ValExpression:
+		res.declareBinding(e, s)
+		res.tags(e.Tags, s)
+		res.patternUses(e.Pattern, s)
+		res.expr(e.Value, s)
+	case *ast.BlockStatement:
+		res.block(e, s)
+	case *ast.PrefixExpression:
+		res.expr(e.Right, s)
+	case *ast.InfixExpression:
+		res.expr(e.Left, s)
+		res.expr(e.Right, s)
+	case *ast.ComparisonChain:
+		res.exprs(e.Operands, s)
+	case *ast.IfExpression:
+		res.expr(e.Condition, s)
+		res.block(e.ThenBranch, s)
+		res.block(e.ElseBranch, s)
+	case *ast.FunctionLiteral:
+		params := newScope(s)
+		res.parameters(e.Parameters, params)
+		res.block(e.Body, params)
+	case *ast.RecurExpression:
+		res.exprs(e.Arguments, s)
+	case *ast.SpawnExpression:
+		res.expr(e.Body, s)
+	case *ast.AwaitExpression:
+		res.expr(e.Value, s)
+	case *ast.CallExpression:
+		res.expr(e.Function, s)
+		res.exprs(e.Arguments, s)
+	case *ast.NamedArgument:
+		res.expr(e.Value, s)
+	case *ast.SpreadExpression:
+		res.expr(e.Value, s)
+	case *ast.ListLiteral:
+		res.exprs(e.Elements, s)
+	case *ast.MapLiteral:
+		for _, key := range e.Keys {
+			res.expr(key, s)
+			res.expr(e.Pairs[key], s)
+		}
+	case *ast.IndexExpression:
+		res.expr(e.Left, s)
+		res.expr(e.Index, s)
+	case *ast.SliceExpression:
+		res.expr(e.Start, s)
+		res.expr(e.End, s)
+		res.expr(e.Step, s)
+	case *ast.StructSchemaExpression:
+		for _, field := range e.Fields {
+			res.expr(field.Default, s)
+		}
+	case *ast.StructInitExpression:
+		res.expr(e.Schema, s)
+		for _, field := range e.Fields {
+			res.expr(field.Value, s)
+		}
+	case *ast.StructCopyExpression:
+		res.expr(e.Source, s)
+		for _, field := range e.Fields {
+			res.expr(field.Value, s)
+		}
+	case *ast.MatchExpression:
+		res.match(e, e.Value != nil, s)
+	case *ast.SelectExpression:
+		for _, c := range e.Cases {
+			if c == nil {
+				continue
+			}
+			res.expr(c.Channel, s)
+			res.expr(c.Value, s)
+			res.expr(c.After, s)
+			res.expr(c.Await, s)
+			// A select handler can be a bare match, which is given the case's value
+			if m, ok := c.Handler.(*ast.MatchExpression); ok {
+				res.match(m, true, s)
+			} else {
+				res.expr(c.Handler, s)
+			}
+		}
+	}
+}
+
+// match resolves each case in its own scope. Without a value the patterns are
+// conditions and bind nothing.
+func (res *resolution) match(m *ast.MatchExpression, valued bool, s *scope) {
+	res.expr(m.Value, s)
+	for _, c := range m.Cases {
+		if c == nil {
+			continue
+		}
+		inner := newScope(s)
+		if valued {
+			res.patternUses(c.Pattern, s)
+			bindPattern(c.Pattern, inner)
+		} else {
+			res.conditionUses(c.Pattern, s)
+		}
+		res.expr(c.Guard, inner)
+		res.block(c.Body, inner)
+	}
+}
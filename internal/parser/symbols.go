@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"slug/internal/ast"
+)
+
+type SymbolKind string
+
+const (
+	SymbolVal     SymbolKind = "val"
+	SymbolVar     SymbolKind = "var"
+	SymbolFn      SymbolKind = "fn"
+	SymbolForeign SymbolKind = "foreign"
+	SymbolStruct  SymbolKind = "struct"
+)
+
+// Symbol is a top-level name declared by a module.
+type Symbol struct {
+	Name     string
+	Kind     SymbolKind
+	Position int // src index of the name
+}
+
+// CollectSymbols lists the top-level declarations of a parsed program in source
+// order without evaluating it. Bindings to a function or struct literal report
+// as fn or struct, destructuring bindings report every name they introduce.
+func CollectSymbols(program *ast.Program) []Symbol {
+	var symbols []Symbol
+	if program == nil {
+		return symbols
+	}
+
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *ast.ForeignFunctionDeclaration:
+			if s.Name != nil {
+				symbols = append(symbols, Symbol{Name: s.Name.Value, Kind: SymbolForeign, Position: s.Name.Token.Position})
+			}
+		case *ast.ExpressionStatement:
+			switch e := s.Expression.(type) {
+			case *ast.ValExpression:
+				symbols = appendPatternSymbols(symbols, e.Pattern, bindingKind(SymbolVal, e.Value))
+			case *ast.VarExpression:
+				symbols = appendPatternSymbols(symbols, e.Pattern, bindingKind(SymbolVar, e.Value))
+			}
+		}
+	}
+	return symbols
+}
+
+func bindingKind(kind SymbolKind, value ast.Expression) SymbolKind {
+	switch value.(type) {
+	case *ast.FunctionLiteral:
+		return SymbolFn
+	case *ast.StructSchemaExpression:
+		return SymbolStruct
+	}
+	return kind
+}
+
+func appendPatternSymbols(symbols []Symbol, pattern ast.MatchPattern, kind SymbolKind) []Symbol {
+	addIdent := func(ident *ast.Identifier) {
+		if ident != nil {
+			symbols = append(symbols, Symbol{Name: ident.Value, Kind: kind, Position: ident.Token.Position})
+		}
+	}
+
+	switch p := pattern.(type) {
+	case *ast.IdentifierPattern:
+		addIdent(p.Value)
+	case *ast.BindingPattern:
+		addIdent(p.Name)
+		symbols = appendPatternSymbols(symbols, p.Pattern, kind)
+	case *ast.StringConcatPattern:
+		symbols = appendPatternSymbols(symbols, p.Rest, kind)
+	case *ast.SpreadPattern:
+		addIdent(p.Value)
+	case *ast.ListPattern:
+		for _, el := range p.Elements {
+			symbols = appendPatternSymbols(symbols, el, kind)
+		}
+	case *ast.MapPattern:
+		for _, entry := range p.Pairs {
+			symbols = appendPatternSymbols(symbols, entry.Pattern, kind)
+		}
+		symbols = appendPatternSymbols(symbols, p.Spread, kind)
+	case *ast.StructPattern:
+		for _, field := range p.Fields {
+			symbols = appendPatternSymbols(symbols, field.Pattern, kind)
+		}
+		symbols = appendPatternSymbols(symbols, p.Spread, kind)
+	}
+	return symbols
+}
@@ -0,0 +1,319 @@
+package runtime
+
+import (
+	"log/slog"
+	"slug/internal/ast"
+	"slug/internal/object"
+	"slug/internal/token"
+)
+
+// compiledExpr evaluates a node that was resolved ahead of time, so running it
+// skips the type switch in Eval. Each closure counts its own step and makes the
+// same calls Eval would for the node, nodes without a closure form defer to Eval.
+type compiledExpr func(e *Task) object.Object
+
+// compiledBlock is a function body compiled to one closure per statement.
+type compiledBlock struct {
+	block *ast.BlockStatement
+	stmts []compiledExpr
+}
+
+// compiledBody returns the compiled form of a function body, building it on the
+// first call. Every closure created from the same literal shares the result.
+func (r *Runtime) compiledBody(body *ast.BlockStatement) *compiledBlock {
+	if body == nil || r.interpretOnly {
+		return nil
+	}
+	if cb, ok := r.compiled.Load(body); ok {
+		return cb.(*compiledBlock)
+	}
+	cb, _ := r.compiled.LoadOrStore(body, compileBlock(body))
+	return cb.(*compiledBlock)
+}
+
+// evalFunctionBody runs a function body in the already pushed block environment.
+func (e *Task) evalFunctionBody(fn *object.Function) object.Object {
+	if cb, ok := fn.Compiled.(*compiledBlock); ok && cb != nil {
+		return cb.runWithinEnv(e)
+	}
+	return e.evalBlockStatementWithinEnv(fn.Body)
+}
+
+func (e *Task) countStep() {
+	if e.Runtime.Config.MaxSteps > 0 {
+		e.Runtime.steps.Add(1)
+	}
+}
+
+// runWithinEnv mirrors evalBlockStatementWithinEnv.
+func (cb *compiledBlock) runWithinEnv(e *Task) object.Object {
+	var result object.Object = object.NIL
+
+	for _, stmt := range cb.stmts {
+		result = stmt(e)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+
+	if result != nil {
+		return result
+	}
+	return object.NIL
+}
+
+func compileBlock(block *ast.BlockStatement) *compiledBlock {
+	cb := &compiledBlock{block: block, stmts: make([]compiledExpr, 0, len(block.Statements))}
+	for _, stmt := range block.Statements {
+		cb.stmts = append(cb.stmts, compileStatement(stmt))
+	}
+	return cb
+}
+
+func compileStatement(stmt ast.Statement) compiledExpr {
+	if s, ok := stmt.(*ast.ExpressionStatement); ok {
+		expr := compileExpr(s.Expression)
+		return func(e *Task) object.Object {
+			e.countStep()
+			return expr(e)
+		}
+	}
+	return func(e *Task) object.Object { return e.Eval(stmt) }
+}
+
+// compileScopedBlock compiles a block evaluated as an expression, such as an
+// if branch, which runs in its own block environment.
+func compileScopedBlock(block *ast.BlockStatement) compiledExpr {
+	cb := compileBlock(block)
+	return func(e *Task) object.Object {
+		e.countStep()
+		blockEnv := e.newBlockEnv(block)
+		e.PushEnv(blockEnv)
+		return e.PopEnv(cb.runWithinEnv(e))
+	}
+}
+
+func compileExpr(node ast.Expression) compiledExpr {
+	switch n := node.(type) {
+	case *ast.NumberLiteral:
+		value := n.Value
+		return func(e *Task) object.Object {
+			e.countStep()
+			return &object.Number{Value: value}
+		}
+
+	case *ast.Boolean:
+		value := object.FALSE
+		if n.Value {
+			value = object.TRUE
+		}
+		return func(e *Task) object.Object {
+			e.countStep()
+			return value
+		}
+
+	case *ast.Nil:
+		return func(e *Task) object.Object {
+			e.countStep()
+			return object.NIL
+		}
+
+	case *ast.Identifier:
+		return func(e *Task) object.Object {
+			e.countStep()
+			return e.evalIdentifier(n)
+		}
+
+	case *ast.PrefixExpression:
+		right := compileExpr(n.Right)
+		return func(e *Task) object.Object {
+			e.countStep()
+			r := right(e)
+			if e.isError(r) {
+				return r
+			}
+			return e.evalPrefixExpression(n.Operator, r)
+		}
+
+	case *ast.InfixExpression:
+		return compileInfix(n)
+
+	case *ast.IfExpression:
+		return compileIf(n)
+
+	case *ast.BlockStatement:
+		if n.IsNursery {
+			break
+		}
+		return compileScopedBlock(n)
+
+	case *ast.CallExpression:
+		if args, ok := compilePositionalArgs(n.Arguments); ok {
+			return compileCall(n, args)
+		}
+
+	case *ast.RecurExpression:
+		if args, ok := compilePositionalArgs(n.Arguments); ok {
+			return compileRecur(n, args)
+		}
+	}
+
+	return func(e *Task) object.Object { return e.Eval(node) }
+}
+
+func compileInfix(n *ast.InfixExpression) compiledExpr {
+	if n.Operator == "=" {
+		return func(e *Task) object.Object { return e.Eval(n) }
+	}
+
+	left := compileExpr(n.Left)
+	if n.Operator == "&&" || n.Operator == "||" || n.Operator == "??" {
+		return func(e *Task) object.Object {
+			e.countStep()
+			l := left(e)
+			if e.isError(l) {
+				return l
+			}
+			return e.evalShortCircuitInfixExpression(l, n)
+		}
+	}
+
+	right := compileExpr(n.Right)
+	custom := n.Token.Type == token.CUSTOM_OPERATOR
+	return func(e *Task) object.Object {
+		e.countStep()
+		l := left(e)
+		if e.isError(l) {
+			return l
+		}
+		r := right(e)
+		if e.isError(r) {
+			return r
+		}
+		if custom {
+			return e.evalCustomOperator(n.Token.Position, n.Operator, l, r)
+		}
+		return e.evalInfixExpression(n.Token.Position, n.Operator, l, r)
+	}
+}
+
+func compileIf(n *ast.IfExpression) compiledExpr {
+	condition := compileExpr(n.Condition)
+	then := compileScopedBlock(n.ThenBranch)
+	var otherwise compiledExpr
+	if n.ElseBranch != nil {
+		otherwise = compileScopedBlock(n.ElseBranch)
+	}
+	return func(e *Task) object.Object {
+		e.countStep()
+		c := condition(e)
+		if e.isError(c) {
+			return c
+		}
+		if e.isTruthy(c) {
+			return then(e)
+		} else if otherwise != nil {
+			return otherwise(e)
+		}
+		return object.NIL
+	}
+}
+
+// compilePositionalArgs compiles call arguments when none are named or spread,
+// the only shape evaluated without evalCallArguments.
+func compilePositionalArgs(args []ast.Expression) ([]compiledExpr, bool) {
+	compiled := make([]compiledExpr, 0, len(args))
+	for _, arg := range args {
+		switch arg.(type) {
+		case *ast.NamedArgument, *ast.SpreadExpression:
+			return nil, false
+		}
+		compiled = append(compiled, compileExpr(arg))
+	}
+	return compiled, true
+}
+
+func evalCompiledArgs(e *Task, args []compiledExpr) ([]object.Object, object.Object) {
+	var positional []object.Object
+	for _, arg := range args {
+		evaluated := arg(e)
+		if e.isError(evaluated) {
+			return nil, evaluated
+		}
+		positional = append(positional, evaluated)
+	}
+	return positional, nil
+}
+
+func compileCall(n *ast.CallExpression, args []compiledExpr) compiledExpr {
+	function := compileExpr(n.Function)
+	return func(e *Task) object.Object {
+		e.countStep()
+		if errObj := e.checkLimits(n.Token.Position); errObj != nil {
+			return errObj
+		}
+
+		fn := function(e)
+		if e.isError(fn) {
+			return fn
+		}
+		if fn == object.NIL {
+			return e.callNilError(n)
+		}
+
+		positional, err := evalCompiledArgs(e, args)
+		if err != nil {
+			return err
+		}
+
+		if n.IsTailCall {
+			slog.Debug("Tail call",
+				slog.Any("function", n.Token.Literal),
+				slog.Any("argument-count", len(positional)))
+
+			return &object.TailCall{
+				FnName:    n.Token.Literal,
+				Function:  fn,
+				Arguments: positional,
+			}
+		}
+
+		slog.Debug("Function call",
+			slog.Any("function", n.Token.Literal),
+			slog.Any("argument-count", len(positional)))
+		return e.ApplyFunction(n.Token.Position, n.Token.Literal, fn, positional, nil)
+	}
+}
+
+func compileRecur(n *ast.RecurExpression, args []compiledExpr) compiledExpr {
+	return func(e *Task) object.Object {
+		e.countStep()
+		if errObj := e.checkLimits(n.Token.Position); errObj != nil {
+			return errObj
+		}
+
+		positional, err := evalCompiledArgs(e, args)
+		if err != nil {
+			return err
+		}
+
+		fnName, fnObj, ok := e.currentCallFrame()
+		if !ok || fnObj == nil {
+			return e.newErrorWithPos(n.Token.Position, "recur used outside of a function")
+		}
+
+		slog.Debug("Tail recur",
+			slog.Any("function", fnName),
+			slog.Any("argument-count", len(positional)))
+
+		return &object.TailCall{
+			FnName:    fnName,
+			Function:  fnObj,
+			Arguments: positional,
+		}
+	}
+}
@@ -68,6 +68,22 @@ func (n *NurseryScope) NoteChildFailure(failed *Task, err object.Object) {
 	}
 }
 
+// Err returns the first child failure recorded by NoteChildFailure, or nil.
+func (n *NurseryScope) Err() object.Object {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.NurseryErr
+}
+
+// TakeErr returns the first child failure and clears it, so it propagates once.
+func (n *NurseryScope) TakeErr() object.Object {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	err := n.NurseryErr
+	n.NurseryErr = nil
+	return err
+}
+
 // WaitChildren blocks until all direct children of this scope have settled
 func (n *NurseryScope) WaitChildren() {
 	n.mu.RLock()
@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+)
+
+// regexCacheSize bounds the compiled patterns a runtime keeps, so a script that
+// builds patterns from data cannot grow the cache without limit.
+const regexCacheSize = 256
+
+// regexCache is a least recently used cache of compiled patterns keyed by their source.
+type regexCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is the most recently used, elements hold *regexEntry
+	entries map[string]*list.Element
+}
+
+type regexEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRegexCache(size int) *regexCache {
+	return &regexCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// compile returns the compiled form of pattern, compiling it on a miss and
+// evicting the least recently used pattern once the cache is full. Patterns that
+// fail to compile are not cached.
+func (c *regexCache) compile(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[pattern]; ok {
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*regexEntry).re, nil
+	}
+	c.mu.Unlock()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[pattern]; ok {
+		// another task compiled it meanwhile
+		c.order.MoveToFront(el)
+		return el.Value.(*regexEntry).re, nil
+	}
+	c.entries[pattern] = c.order.PushFront(&regexEntry{pattern: pattern, re: re})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*regexEntry).pattern)
+	}
+	return re, nil
+}
+
+// len reports the number of cached patterns.
+func (c *regexCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -1,11 +1,15 @@
 package runtime
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slug/internal/ast"
 	"slug/internal/foreign"
 	"slug/internal/lexer"
@@ -13,7 +17,9 @@ import (
 	"slug/internal/parser"
 	"slug/internal/util"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Runtime struct {
@@ -23,7 +29,32 @@ type Runtime struct {
 	ForeignFunctions map[string]*object.Foreign
 	FullSchema       *object.StructSchema
 	EmptySchema      *object.StructSchema
+	moduleSchemas    map[string][]*object.StructSchema
+	Stdout           io.Writer // destination of print and println, os.Stdout by default
+	Stdin            io.Reader // source of slug.io.stdin, os.Stdin by default
+	Stderr           io.Writer // destination of runtime warnings, os.Stderr by default
+	outMu            sync.Mutex
+	inMu             sync.Mutex
+	in               *bufio.Reader
+	inSrc            io.Reader
 	nextID           atomic.Int64
+	steps            atomic.Int64
+	rngMu            sync.Mutex
+	rng              *rand.Rand
+	allowedBuiltins  map[string]bool // nil when every builtin is allowed
+	deadline         time.Time       // zero when the run has no deadline
+	deprecatedSeen   sync.Map        // *object.Function -> struct{}, deprecated functions already warned about
+	parseMu          sync.Mutex
+	parseCache       map[string]parsedModule // keyed by absolute module path
+	compiled         sync.Map                // *ast.BlockStatement -> *compiledBlock, function bodies compiled so far
+	interpretOnly    bool                    // evaluate function bodies without compiling them, for comparison
+	regexes          *regexCache             // compiled patterns used by slug.regex, bounded
+}
+
+type parsedModule struct {
+	modTime time.Time
+	source  []byte
+	program *ast.Program
 }
 
 func NewRuntime(config util.Configuration) *Runtime {
@@ -31,6 +62,7 @@ func NewRuntime(config util.Configuration) *Runtime {
 	config.Store = util.NewConfigStore(config.RootPath, config.SlugHome, config.MainModule, config.Argv)
 
 	builtinFunctions := map[string]*object.Foreign{
+		"apply":      fnBuiltinApply(),
 		"argv":       fnBuiltinArgv(),
 		"argm":       fnBuiltinArgm(),
 		"cfg":        fnBuiltinCfg(),
@@ -38,35 +70,125 @@ func NewRuntime(config util.Configuration) *Runtime {
 		"len":        fnBuiltinLen(),
 		"print":      fnBuiltinPrint(),
 		"println":    fnBuiltinPrintLn(),
+		"repr":       fnBuiltinRepr(),
 		"stacktrace": fnBuiltinStacktrace(),
+		"timeIt":     fnBuiltinTimeIt(),
 	}
 
 	functions := getForeignFunctions()
+	functions["slug.channel.atomicCounter"] = fnChannelAtomicCounter()
 	functions["slug.channel.chan"] = fnChannelChan()
 	functions["slug.channel.close"] = fnChannelClose()
+	functions["slug.channel.counterGet"] = fnChannelCounterGet()
+	functions["slug.channel.counterSet"] = fnChannelCounterSet()
+	functions["slug.channel.decr"] = fnChannelDecr()
+	functions["slug.channel.incr"] = fnChannelIncr()
+	functions["slug.io.stdin.readLine"] = fnStdinReadLine()
+	functions["slug.io.stdin.read"] = fnStdinRead()
+
+	var allowedBuiltins map[string]bool
+	if config.AllowedBuiltins != nil {
+		allowedBuiltins = make(map[string]bool, len(config.AllowedBuiltins))
+		for _, name := range config.AllowedBuiltins {
+			allowedBuiltins[name] = true
+		}
+	}
+
+	deadline := config.Deadline
+	if config.Timeout > 0 {
+		if d := time.Now().Add(config.Timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	fullSchema := &object.StructSchema{
+		Name:       "Full",
+		Fields:     []object.StructSchemaField{{Name: "value"}},
+		FieldIndex: map[string]int{"value": 0},
+	}
+	emptySchema := &object.StructSchema{
+		Name:       "Empty",
+		Fields:     []object.StructSchemaField{},
+		FieldIndex: map[string]int{},
+	}
 
 	return &Runtime{
 		Config:           config,
 		Modules:          nil,
 		Builtins:         builtinFunctions,
+		Stdout:           os.Stdout,
+		Stdin:            os.Stdin,
+		Stderr:           os.Stderr,
 		ForeignFunctions: functions,
-		FullSchema: &object.StructSchema{
-			Name:       "Full",
-			Fields:     []object.StructSchemaField{{Name: "value"}},
-			FieldIndex: map[string]int{"value": 0},
-		},
-		EmptySchema: &object.StructSchema{
-			Name:       "Empty",
-			Fields:     []object.StructSchemaField{},
-			FieldIndex: map[string]int{},
+		FullSchema:       fullSchema,
+		EmptySchema:      emptySchema,
+		// bound in a module before its source runs: LoadModule defines them, ModuleNames lists them
+		moduleSchemas: map[string][]*object.StructSchema{
+			"slug.channel": {fullSchema, emptySchema},
 		},
+		rng:             rand.New(rand.NewSource(seed)),
+		allowedBuiltins: allowedBuiltins,
+		deadline:        deadline,
+		regexes:         newRegexCache(regexCacheSize),
 	}
 }
 
+// BuiltinAllowed reports whether the sandbox policy in Config.AllowedBuiltins permits name.
+func (r *Runtime) BuiltinAllowed(name string) bool {
+	return r.allowedBuiltins == nil || r.allowedBuiltins[name]
+}
+
+// Steps returns the number of evaluation steps taken so far, counted only when Config.MaxSteps is set.
+func (r *Runtime) Steps() int64 {
+	return r.steps.Load()
+}
+
+// WriteOutput writes s to Stdout, serialising writes from concurrent tasks.
+func (r *Runtime) WriteOutput(s string) {
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+	io.WriteString(r.Stdout, s)
+}
+
+// stdinReader returns the buffered reader over Stdin, replacing it if Stdin has been swapped.
+// Callers must hold inMu.
+func (r *Runtime) stdinReader() *bufio.Reader {
+	if r.in == nil || r.inSrc != r.Stdin {
+		r.in = bufio.NewReader(r.Stdin)
+		r.inSrc = r.Stdin
+	}
+	return r.in
+}
+
 func (r *Runtime) NextHandleID() int64 {
 	return r.nextID.Add(1)<<16 | int64(rand.Intn(0xFFFF))
 }
 
+// RandomBytes fills p from the runtime random source, which is reproducible when Config.Seed is set.
+func (r *Runtime) RandomBytes(p []byte) {
+	r.rngMu.Lock()
+	defer r.rngMu.Unlock()
+	r.rng.Read(p)
+}
+
+// CompileRegex returns the compiled form of pattern from this runtime's bounded cache,
+// so regex functions called in a loop don't recompile the same pattern on every call.
+func (r *Runtime) CompileRegex(pattern string) (*regexp.Regexp, error) {
+	return r.regexes.compile(pattern)
+}
+
+// RegisterForeign binds fn to the fully qualified name of a `foreign` declaration (for example
+// "slug.std.keys") in this runtime only. Modules loaded afterwards by this runtime resolve the
+// declaration to fn; other runtimes keep their own registry.
+func (r *Runtime) RegisterForeign(fqn string, fn *object.Foreign) {
+	r.ForeignFunctions[fqn] = fn
+}
+
 func (r *Runtime) LookupForeign(name string) (*object.Foreign, bool) {
 	if fn, ok := r.ForeignFunctions[name]; ok {
 		return fn, true
@@ -90,40 +212,32 @@ func (r *Runtime) LoadModule(modName string) (*object.Module, error) {
 	pathParts := strings.Split(modName, ".")
 	relPath := filepath.Join(pathParts...) + ".slug"
 
-	// 2. Search Paths: Check local RootPath, then SLUG_HOME/lib
+	// 2. Search Paths: Check local RootPath, then ModulePaths in order, then SLUG_HOME/lib
 	var fullPath string
-	var source []byte
+	var info os.FileInfo
 	var err error
 
-	// Try local RootPath (directory of the entry script)
-	fullPath = filepath.Join(r.Config.RootPath, relPath)
-	source, errFirst := os.ReadFile(fullPath)
-
-	if errFirst != nil {
-		//// Fallback to $SLUG_HOME/lib
-		if r.Config.SlugHome != "" {
-			fullPath = filepath.Join(r.Config.SlugHome, "lib", relPath)
-			source, err = os.ReadFile(fullPath)
-			if err != nil {
-				return nil, fmt.Errorf("could not load module %s: local error: %v, lib error: %v", modName, errFirst, err)
-			}
-		} else {
-			return nil, fmt.Errorf("could not load module %s: %v (SLUG_HOME not set)", modName, errFirst)
+	var tried []string
+	for _, dir := range r.searchPaths() {
+		fullPath = filepath.Join(dir, relPath)
+		info, err = os.Stat(fullPath)
+		if err == nil {
+			break
 		}
+		tried = append(tried, fullPath)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("could not load module %s, tried:\n  %s", modName, strings.Join(tried, "\n  "))
+		if r.Config.SlugHome == "" {
+			msg += "\n(SLUG_HOME not set)"
+		}
+		return nil, errors.New(msg)
 	}
 
-	// 3. Tokenize and Parse
-	l := lexer.New(string(source))
-	p := parser.New(l, fullPath, string(source))
-	program := p.ParseProgram()
-
-	if len(p.Errors()) > 0 {
-		slog.Warn("Error loading module",
-			slog.String("name", modName),
-			slog.String("fullPath", fullPath),
-			slog.String("errors", strings.Join(p.Errors(), "\n")),
-		)
-		return nil, fmt.Errorf("parse errors in module %s:\n%s", modName, strings.Join(p.Errors(), "\n"))
+	// 3. Tokenize and Parse, reusing an earlier parse of the same file if it is unchanged
+	source, program, err := r.parseModuleFile(modName, fullPath, info.ModTime())
+	if err != nil {
+		return nil, err
 	}
 
 	if r.Config.DebugJsonAST {
@@ -153,12 +267,9 @@ func (r *Runtime) LoadModule(modName string) (*object.Module, error) {
 	moduleEnv.Path = fullPath
 	moduleEnv.ModuleFqn = modName
 	moduleEnv.Src = string(source)
-	if modName == "slug.channel" {
-		if _, err := moduleEnv.DefineConstant("Full", r.FullSchema, true, false); err != nil {
-			return nil, fmt.Errorf("failed to install channel schema for module %s: %w", modName, err)
-		}
-		if _, err := moduleEnv.DefineConstant("Empty", r.EmptySchema, true, false); err != nil {
-			return nil, fmt.Errorf("failed to install channel schema for module %s: %w", modName, err)
+	for _, schema := range r.moduleSchemas[modName] {
+		if _, err := moduleEnv.DefineConstant(schema.Name, schema, true, false); err != nil {
+			return nil, fmt.Errorf("failed to install %s schema for module %s: %w", schema.Name, modName, err)
 		}
 	}
 
@@ -202,6 +313,91 @@ func (r *Runtime) LoadModule(modName string) (*object.Module, error) {
 	return module, nil
 }
 
+// parseModuleFile reads and parses the module source at path. Programs are cached by absolute path
+// and modification time so a runtime that loads the same file again, for example after its module
+// cache is reset by an embedder, skips the parse.
+func (r *Runtime) parseModuleFile(modName, path string, modTime time.Time) ([]byte, *ast.Program, error) {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+
+	r.parseMu.Lock()
+	defer r.parseMu.Unlock()
+
+	if cached, ok := r.parseCache[key]; ok && cached.modTime.Equal(modTime) {
+		slog.Info("Module parse loaded from cache",
+			slog.String("name", modName),
+			slog.String("fullPath", path))
+		return cached.source, cached.program, nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load module %s: %v", modName, err)
+	}
+
+	program, errs := parseSource(path, string(source))
+	if len(errs) > 0 {
+		slog.Warn("Error loading module",
+			slog.String("name", modName),
+			slog.String("fullPath", path),
+			slog.String("errors", strings.Join(errs, "\n")),
+		)
+		return nil, nil, fmt.Errorf("parse errors in module %s:\n%s", modName, strings.Join(errs, "\n"))
+	}
+
+	if r.parseCache == nil {
+		r.parseCache = make(map[string]parsedModule)
+	}
+	r.parseCache[key] = parsedModule{modTime: modTime, source: source, program: program}
+	return source, program, nil
+}
+
+// parseSource parses a module, it is a variable so tests can observe parsing.
+var parseSource = func(path, src string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(src), path, src)
+	program := p.ParseProgram()
+	return program, p.Errors()
+}
+
+// ModuleNames lists the top-level names of a module without evaluating it, for
+// static checks of `var {*} = import(...)`. It reports false when the module
+// cannot be found or parsed.
+func (r *Runtime) ModuleNames(modName string) ([]string, bool) {
+	relPath := filepath.Join(strings.Split(modName, ".")...) + ".slug"
+	for _, dir := range r.searchPaths() {
+		fullPath := filepath.Join(dir, relPath)
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			continue
+		}
+		_, program, err := r.parseModuleFile(modName, fullPath, info.ModTime())
+		if err != nil {
+			return nil, false
+		}
+		var names []string
+		for _, schema := range r.moduleSchemas[modName] {
+			names = append(names, schema.Name)
+		}
+		for _, sym := range parser.CollectSymbols(program) {
+			names = append(names, sym.Name)
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// searchPaths lists the directories LoadModule searches, in order.
+func (r *Runtime) searchPaths() []string {
+	paths := []string{r.Config.RootPath}
+	paths = append(paths, r.Config.ModulePaths...)
+	if r.Config.SlugHome != "" {
+		paths = append(paths, filepath.Join(r.Config.SlugHome, "lib"))
+	}
+	return paths
+}
+
 func predeclareTopLevel(program *ast.Program, env *object.Environment) error {
 	for _, stmt := range program.Statements {
 		// Statements may be wrapped in ExpressionStatement
@@ -240,6 +436,9 @@ func predeclarePattern(pat ast.MatchPattern, isConst bool, isExport bool, env *o
 		}
 		return predeclarePattern(p.Pattern, isConst, isExport, env)
 
+	case *ast.StringConcatPattern:
+		return predeclarePattern(p.Rest, isConst, isExport, env)
+
 	case *ast.IdentifierPattern:
 		name := p.Value.Value
 		if isConst {
@@ -310,6 +509,8 @@ func hasExportTag(tags []*ast.Tag) bool {
 	return false
 }
 
+// getForeignFunctions builds a fresh registry for a runtime. The Foreign values are created per
+// call because declaring a foreign in a module fills in its tags and parameters in place.
 func getForeignFunctions() map[string]*object.Foreign {
 	foreignFunctions := map[string]*object.Foreign{}
 	for k, v := range foreign.GetForeignFunctions() {
@@ -0,0 +1,935 @@
+package runtime
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slug/internal/ast"
+	"slug/internal/dec64"
+	"slug/internal/lexer"
+	"slug/internal/object"
+	"slug/internal/parser"
+	"slug/internal/util"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func evalWithEnv(t *testing.T, env *object.Environment, src string) object.Object {
+	t.Helper()
+	return evalWithRuntime(t, NewRuntime(util.Configuration{DefaultLimit: 4}), env, src)
+}
+
+func evalWithRuntime(t *testing.T, rt *Runtime, env *object.Environment, src string) object.Object {
+	t.Helper()
+
+	p := parser.New(lexer.New(src), "test.slug", src)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	env.Path = "test.slug"
+	env.Src = src
+
+	task := &Task{Runtime: rt}
+	task.PushNurseryScope(&NurseryScope{Limit: make(chan struct{}, 4)})
+	task.PushEnv(env)
+	return task.PopEnv(task.Eval(program))
+}
+
+func TestDefineForeignHostFunction(t *testing.T) {
+	env := object.NewRootEnvironment(4)
+	_, err := env.DefineForeign("hostAdd", func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+		}
+		a := args[0].(*object.Number).Value
+		b := args[1].(*object.Number).Value
+		return &object.Number{Value: a.Add(b)}
+	})
+	if err != nil {
+		t.Fatalf("DefineForeign failed: %v", err)
+	}
+
+	result := evalWithEnv(t, env, "var x = 40\nx /> hostAdd(2)")
+
+	num, ok := result.(*object.Number)
+	if !ok {
+		t.Fatalf("result is not a Number. got=%T (%s)", result, result.Inspect())
+	}
+	if !num.Value.Eq(dec64.FromInt64(42)) {
+		t.Errorf("wrong result. got=%s, want=42", num.Inspect())
+	}
+}
+
+func TestDefineForeignHostError(t *testing.T) {
+	env := object.NewRootEnvironment(4)
+	_, _ = env.DefineForeign("hostFail", func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		return ctx.NewError("host failure")
+	})
+
+	result := evalWithEnv(t, env, "hostFail()")
+
+	if _, ok := result.(*object.RuntimeError); !ok {
+		t.Fatalf("expected a RuntimeError. got=%T (%s)", result, result.Inspect())
+	}
+}
+
+func TestDefersRunWhenForeignPanics(t *testing.T) {
+	env := object.NewRootEnvironment(4)
+	_, _ = env.DefineForeign("hostPanic", func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		panic("host exploded")
+	})
+
+	src := `
+var log = []
+var f = fn() {
+	defer log = log :+ "always"
+	defer onerror(err) { log = log :+ err.msg; throw err }
+	hostPanic()
+	log = log :+ "unreachable"
+}
+f()
+`
+	result := evalWithEnv(t, env, src)
+	if _, ok := result.(*object.RuntimeError); !ok {
+		t.Fatalf("expected a RuntimeError. got=%T (%s)", result, result.Inspect())
+	}
+	if !strings.Contains(result.Inspect(), "host exploded") {
+		t.Errorf("error does not carry the panic value: %s", result.Inspect())
+	}
+
+	got, ok := env.Get("log")
+	if !ok {
+		t.Fatalf("log is not defined")
+	}
+	log, ok := got.(*object.List)
+	if !ok || len(log.Elements) != 2 {
+		t.Fatalf("expected the onerror and always defers to run once each. got=%s", got.Inspect())
+	}
+	msg := log.Elements[0].Inspect()
+	if !strings.Contains(msg, "foreign function 'hostPanic' panicked: host exploded") {
+		t.Errorf("onerror did not see the panic first. got=%s", msg)
+	}
+	if !strings.Contains(msg, "test.slug:6:11") {
+		t.Errorf("panic error does not carry the call position. got=%s", msg)
+	}
+	if log.Elements[1].Inspect() != "always" {
+		t.Errorf("always defer did not run last. got=%s", got.Inspect())
+	}
+}
+
+func TestForeignRegistryIsPerRuntime(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "host"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	module := "@export\nforeign greet = fn()\n"
+	if err := os.WriteFile(filepath.Join(root, "host", "hello.slug"), []byte(module), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	greeter := func(msg string) *object.Foreign {
+		return &object.Foreign{
+			Name: "greet",
+			Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+				return &object.String{Value: msg}
+			},
+		}
+	}
+
+	rt1 := NewRuntime(util.Configuration{RootPath: root, DefaultLimit: 4})
+	rt1.RegisterForeign("host.hello.greet", greeter("hello from one"))
+	rt2 := NewRuntime(util.Configuration{RootPath: root, DefaultLimit: 4})
+	rt2.RegisterForeign("host.hello.greet", greeter("hello from two"))
+	rt3 := NewRuntime(util.Configuration{RootPath: root, DefaultLimit: 4})
+
+	src := "var {greet} = import(\"host.hello\")\ngreet()"
+	for rt, want := range map[*Runtime]string{rt1: "hello from one", rt2: "hello from two"} {
+		result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), src)
+		str, ok := result.(*object.String)
+		if !ok {
+			t.Fatalf("result is not a String. got=%T (%s)", result, result.Inspect())
+		}
+		if str.Value != want {
+			t.Errorf("wrong greeting. got=%q, want=%q", str.Value, want)
+		}
+	}
+
+	if _, ok := rt3.LookupForeign("host.hello.greet"); ok {
+		t.Errorf("foreign registered on another runtime leaked into a new runtime")
+	}
+	result := evalWithRuntime(t, rt3, object.NewRootEnvironment(4), src)
+	if result.Type() != object.ERROR_OBJ {
+		t.Errorf("expected an error loading an unregistered foreign. got=%s", result.Inspect())
+	}
+}
+
+func TestRawArgsForeignReceivesArgumentsUnbound(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "host"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	module := "@export\nforeign inspect = fn(...args)\n"
+	if err := os.WriteFile(filepath.Join(root, "host", "raw.slug"), []byte(module), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := NewRuntime(util.Configuration{RootPath: root, DefaultLimit: 4})
+	rt.RegisterForeign("host.raw.inspect", &object.Foreign{
+		Name:    "inspect",
+		RawArgs: true,
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("expected 2 raw arguments, got %d", len(args))
+			}
+			return &object.List{Elements: args}
+		},
+	})
+
+	tests := []struct {
+		call     string
+		expected string
+	}{
+		{"inspect()", "[[], {}]"},
+		{"inspect(1, [2, 3])", "[[1, [2, 3]], {}]"},
+		{"inspect(1, flag = true, label: \"x\")", "[[1], {:flag: true, :label: x}]"},
+	}
+	for _, tt := range tests {
+		src := "var {inspect} = import(\"host.raw\")\n" + tt.call
+		result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), src)
+		if result.Inspect() != tt.expected {
+			t.Errorf("%s: got=%s, want=%s", tt.call, result.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestAllowedBuiltinsSandbox(t *testing.T) {
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4, AllowedBuiltins: []string{"len"}})
+
+	result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), "len([1, 2, 3])")
+	num, ok := result.(*object.Number)
+	if !ok || !num.Value.Eq(dec64.FromInt64(3)) {
+		t.Fatalf("allowed builtin failed. got=%s", result.Inspect())
+	}
+
+	for _, src := range []string{`import("slug.std")`, `println("hi")`} {
+		result = evalWithRuntime(t, rt, object.NewRootEnvironment(4), src)
+		errObj, ok := result.(*object.Error)
+		if !ok {
+			t.Fatalf("denied builtin did not error for %s. got=%s", src, result.Inspect())
+		}
+		if !strings.Contains(errObj.Message, "is not allowed by the sandbox policy") {
+			t.Errorf("unexpected error message: %s", errObj.Message)
+		}
+	}
+}
+
+func TestStrictModes(t *testing.T) {
+	cases := []struct {
+		src    string
+		config util.Configuration
+		errMsg string
+	}{
+		{"[1, 2][2]", util.Configuration{StrictIndex: true}, "index 2 out of range for list of length 2"},
+		{"[1, 2][-3]", util.Configuration{StrictIndex: true}, "index -3 out of range for list of length 2"},
+		{`"ab"[5]`, util.Configuration{StrictIndex: true}, "index 5 out of range for string of length 2"},
+		{`0x"ff"[1]`, util.Configuration{StrictIndex: true}, "index 1 out of range for bytes of length 1"},
+		{"match 3 { 1 => :one }", util.Configuration{StrictMatch: true}, "no match case matched 3"},
+		{"[1, 2][2]", util.Configuration{StrictMatch: true}, ""},
+		{"match 3 { 1 => :one }", util.Configuration{StrictIndex: true}, ""},
+	}
+
+	for _, c := range cases {
+		c.config.DefaultLimit = 4
+		result := evalWithRuntime(t, NewRuntime(c.config), object.NewRootEnvironment(4), c.src)
+		if c.errMsg == "" {
+			if result != object.NIL {
+				t.Errorf("%s: expected nil, got %s", c.src, result.Inspect())
+			}
+			continue
+		}
+		errObj, ok := result.(*object.Error)
+		if !ok || !strings.Contains(errObj.Message, c.errMsg) {
+			t.Errorf("%s: expected error %q, got %s", c.src, c.errMsg, result.Inspect())
+		}
+	}
+}
+
+func TestMaxStepsBudget(t *testing.T) {
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4, MaxSteps: 5000})
+	result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), "var add = fn(a, b) { a + b }\nadd(1, 2)")
+	num, ok := result.(*object.Number)
+	if !ok || !num.Value.Eq(dec64.FromInt64(3)) {
+		t.Fatalf("small program failed under budget. got=%s", result.Inspect())
+	}
+
+	src := `
+var deferred = false
+var spin = fn(n) { recur(n + 1) }
+var run = fn() {
+	defer { deferred = true }
+	spin(0)
+}
+run()
+`
+	rt = NewRuntime(util.Configuration{DefaultLimit: 4, MaxSteps: 5000})
+	env := object.NewRootEnvironment(4)
+	result = evalWithRuntime(t, rt, env, src)
+	errObj, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("expected budget error. got=%T (%s)", result, result.Inspect())
+	}
+	if !strings.Contains(errObj.Message, "computation budget exceeded") {
+		t.Errorf("unexpected error message: %s", errObj.Message)
+	}
+	if !strings.Contains(errObj.Message, "test.slug:3") {
+		t.Errorf("budget error is missing its position: %s", errObj.Message)
+	}
+	if deferred, _ := env.Get("deferred"); deferred != object.TRUE {
+		t.Errorf("defer did not run while unwinding the budget error")
+	}
+}
+
+func TestTimeoutAbortsLongComputation(t *testing.T) {
+	src := `
+var spin = fn(n) { recur(n + 1) }
+var run = nursery fn() {
+	var a = spawn { spin(0) }
+	var b = spawn { spin(0) }
+	spin(0)
+}
+run()
+`
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4, Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), src)
+	elapsed := time.Since(start)
+
+	if !strings.Contains(result.Inspect(), "deadline exceeded") {
+		t.Fatalf("expected deadline error. got=%T (%s)", result, result.Inspect())
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("evaluation was not aborted promptly, took %s", elapsed)
+	}
+}
+
+func TestForeignSeesTaskCancellation(t *testing.T) {
+	env := object.NewRootEnvironment(4)
+	stopped := make(chan bool, 1)
+	_, _ = env.DefineForeign("hostBlock", func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		select {
+		case <-ctx.Context().Done():
+			stopped <- ctx.Context().Err() != nil
+		case <-time.After(5 * time.Second):
+			stopped <- false
+		}
+		return ctx.Nil()
+	})
+
+	src := `
+var run = nursery fn() {
+	var h = spawn { hostBlock() }
+	select {
+		await h /> fn(_) { :finished }
+		after 20 /> fn(_) { :timedOut }
+	}
+}
+run()
+`
+	result := evalWithEnv(t, env, src)
+	if result.Inspect() != ":timedOut" {
+		t.Fatalf("expected the await to time out. got=%T (%s)", result, result.Inspect())
+	}
+
+	select {
+	case ok := <-stopped:
+		if !ok {
+			t.Errorf("foreign call ran to completion instead of seeing the cancellation")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("foreign call did not return after its task was cancelled")
+	}
+}
+
+func TestCancelledTaskStopsSleeping(t *testing.T) {
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4})
+	env := object.NewRootEnvironment(4)
+	var ticks atomic.Int64
+	_, _ = env.DefineForeign("tick", func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		ticks.Add(1)
+		return ctx.Nil()
+	})
+	_, _ = env.DefineForeign("sleep", rt.ForeignFunctions["slug.time.sleep"].Fn)
+
+	src := `
+var run = nursery fn() {
+	var h = spawn {
+		var loop = fn() { tick(); sleep(30); recur() }
+		loop()
+	}
+	select {
+		await h /> fn(_) { :finished }
+		after 20 /> fn(_) { :timedOut }
+	}
+}
+run()
+`
+	result := evalWithRuntime(t, rt, env, src)
+	if result.Inspect() != ":timedOut" {
+		t.Fatalf("expected the await to time out. got=%T (%s)", result, result.Inspect())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	settled := ticks.Load()
+	time.Sleep(200 * time.Millisecond)
+	if n := ticks.Load(); n != settled || n > 3 {
+		t.Errorf("cancelled task kept looping: %d ticks, then %d", settled, n)
+	}
+}
+
+func TestPrintWritesToRuntimeStdout(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4})
+	rt.Stdout = &out
+
+	evalWithRuntime(t, rt, object.NewRootEnvironment(4), `print("a", 1, [2, 3])
+println()
+println("b", {k: "v"})
+print("c")`)
+
+	want := "a 1 [2, 3]\nb {:k: v}\nc"
+	if out.String() != want {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), want)
+	}
+}
+
+func TestDisplayPrecisionOnlyAffectsPrint(t *testing.T) {
+	src := `var x = 1.23456
+println(x, [x * 2], {n: x})
+var joined = "" + x
+println(joined == "1.23456", x == 1.23456)`
+
+	cases := []struct {
+		precision int
+		want      string
+	}{
+		{2, "1.23 [2.47] {:n: 1.23}\ntrue true\n"},
+		{4, "1.2346 [2.4691] {:n: 1.2346}\ntrue true\n"},
+		{0, "1.23456 [2.46912] {:n: 1.23456}\ntrue true\n"},
+	}
+	// build every runtime first, the precision of one must not leak into another
+	runtimes := make([]*Runtime, len(cases))
+	for i, c := range cases {
+		runtimes[i] = NewRuntime(util.Configuration{DefaultLimit: 4, DisplayPrecision: c.precision})
+	}
+
+	for i, c := range cases {
+		var out bytes.Buffer
+		runtimes[i].Stdout = &out
+
+		evalWithRuntime(t, runtimes[i], object.NewRootEnvironment(4), src)
+
+		if out.String() != c.want {
+			t.Errorf("precision %d: wrong output. got=%q, want=%q", c.precision, out.String(), c.want)
+		}
+	}
+}
+
+func TestRegexCacheIsBoundedPerRuntime(t *testing.T) {
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4})
+	first, err := rt.CompileRegex("p0")
+	if err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+	if again, _ := rt.CompileRegex("p0"); again != first {
+		t.Errorf("expected a repeated pattern to come from the cache")
+	}
+
+	for i := 1; i <= regexCacheSize; i++ {
+		if _, err := rt.CompileRegex("p" + strings.Repeat("x", i)); err != nil {
+			t.Fatalf("unexpected compile error: %v", err)
+		}
+	}
+	if n := rt.regexes.len(); n != regexCacheSize {
+		t.Errorf("expected the cache to stop at %d patterns, got %d", regexCacheSize, n)
+	}
+	if again, _ := rt.CompileRegex("p0"); again == first {
+		t.Errorf("expected the least recently used pattern to be evicted")
+	}
+
+	if _, err := rt.CompileRegex("("); err == nil {
+		t.Errorf("expected an invalid pattern to fail")
+	}
+	if n := NewRuntime(util.Configuration{DefaultLimit: 4}).regexes.len(); n != 0 {
+		t.Errorf("expected a new runtime to start with an empty cache, got %d", n)
+	}
+}
+
+func TestSeededRuntimesRepeatUUIDs(t *testing.T) {
+	uuids := func(seed int64) string {
+		rt := NewRuntime(util.Configuration{DefaultLimit: 4, SlugHome: filepath.Join("..", ".."), Seed: seed})
+		result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), `
+var {*} = import("slug.crypto")
+[uuid(), uuid(), uuid()]
+`)
+		if _, ok := result.(*object.List); !ok {
+			t.Fatalf("expected a list of uuids, got %s", result.Inspect())
+		}
+		return result.Inspect()
+	}
+
+	first := uuids(42)
+	if again := uuids(42); again != first {
+		t.Errorf("same seed gave different uuids:\n%s\n%s", first, again)
+	}
+	if other := uuids(43); other == first {
+		t.Errorf("different seeds gave the same uuids: %s", first)
+	}
+}
+
+func TestStdinReadsFromRuntimeReader(t *testing.T) {
+	var out bytes.Buffer
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4, SlugHome: filepath.Join("..", "..")})
+	rt.Stdin = strings.NewReader("first line\r\nsecond\nabcdef")
+	rt.Stdout = &out
+
+	evalWithRuntime(t, rt, object.NewRootEnvironment(4), `
+var {*} = import("slug.io.stdin")
+println(readLine())
+println(readLine())
+println(read(4))
+println(read(4))
+println(read(4))
+println(readLine())
+`)
+
+	want := "first line\nsecond\n0x\"61626364\"\n0x\"6566\"\nnil\nnil\n"
+	if out.String() != want {
+		t.Errorf("wrong output. got=%q, want=%q", out.String(), want)
+	}
+}
+
+func TestLoadModuleSearchesModulePaths(t *testing.T) {
+	root := t.TempDir()
+	extra1 := t.TempDir()
+	extra2 := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(extra2, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extra2, "pkg", "util.slug"), []byte("@export\nval answer = 42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := NewRuntime(util.Configuration{RootPath: root, ModulePaths: []string{extra1, extra2}, DefaultLimit: 4})
+	module, err := rt.LoadModule("pkg.util")
+	if err != nil {
+		t.Fatalf("module not found on secondary path: %v", err)
+	}
+	if module.Path != filepath.Join(extra2, "pkg", "util.slug") {
+		t.Errorf("module loaded from wrong path: %s", module.Path)
+	}
+
+	_, err = rt.LoadModule("pkg.missing")
+	if err == nil {
+		t.Fatalf("expected an error loading a missing module")
+	}
+	for _, dir := range []string{root, extra1, extra2} {
+		if want := filepath.Join(dir, "pkg", "missing.slug"); !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not list %s: %v", want, err)
+		}
+	}
+}
+
+func TestLoadModuleReusesParse(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "cached.slug")
+	if err := os.WriteFile(path, []byte("@export\nval answer = 42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parses := 0
+	original := parseSource
+	parseSource = func(path, src string) (*ast.Program, []string) {
+		parses++
+		return original(path, src)
+	}
+	defer func() { parseSource = original }()
+
+	rt := NewRuntime(util.Configuration{RootPath: root, DefaultLimit: 4})
+	for i := 0; i < 2; i++ {
+		rt.Modules = nil
+		if _, err := rt.LoadModule("cached"); err != nil {
+			t.Fatalf("load %d failed: %v", i, err)
+		}
+	}
+	if parses != 1 {
+		t.Errorf("module parsed %d times, want 1", parses)
+	}
+
+	// a changed file is parsed again
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	rt.Modules = nil
+	if _, err := rt.LoadModule("cached"); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if parses != 2 {
+		t.Errorf("modified module parsed %d times in total, want 2", parses)
+	}
+}
+
+func TestDeprecatedWarnsOnce(t *testing.T) {
+	var errOut bytes.Buffer
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4})
+	rt.Stderr = &errOut
+
+	result := evalWithRuntime(t, rt, object.NewRootEnvironment(4), `
+@deprecated("use plus instead")
+var add = fn(a, b) { a + b }
+add(1, 2)
+add(3, 4)
+`)
+	num, ok := result.(*object.Number)
+	if !ok || !num.Value.Eq(dec64.FromInt64(7)) {
+		t.Fatalf("deprecated function result wrong. got=%s", result.Inspect())
+	}
+
+	if got := strings.Count(errOut.String(), "Warning:"); got != 1 {
+		t.Fatalf("expected 1 deprecation warning, got %d: %q", got, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "call to deprecated function: use plus instead") ||
+		!strings.Contains(errOut.String(), "test.slug:4:") {
+		t.Errorf("warning missing message or call site: %q", errOut.String())
+	}
+}
+
+func TestRunTestsReportsPassAndFail(t *testing.T) {
+	path := filepath.Join("testdata", "sample_tests.slug")
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4, SlugHome: filepath.Join("..", "..")})
+	env := object.NewRootEnvironment(4)
+	rt.Modules = map[string]*object.Module{"sample_tests": {Name: "sample_tests", Path: path, Env: env}}
+	if res := evalWithRuntime(t, rt, env, string(src)); res != nil && res.Type() == object.ERROR_OBJ {
+		t.Fatalf("evaluating sample failed: %s", res.Inspect())
+	}
+
+	task := &Task{Runtime: rt}
+	task.PushNurseryScope(&NurseryScope{Limit: make(chan struct{}, 4)})
+	results, err := task.RunTests("sample_tests")
+	if err != nil {
+		t.Fatalf("RunTests failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	failed := WriteTestReport(&out, results)
+	if failed != 2 || len(results) != 4 {
+		t.Fatalf("expected 4 tests with 2 failures, got %d with %d failures:\n%s", len(results), failed, out.String())
+	}
+	if !strings.Contains(out.String(), "Tests run: 4, Passed: 2, Failed: 2") {
+		t.Fatalf("unexpected summary:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "[FAIL] failsAssertion(): ") || !strings.Contains(out.String(), "expected failure") {
+		t.Fatalf("expected the assertion message in the report:\n%s", out.String())
+	}
+}
+
+func TestListDestructuringLengthErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"val [a, b, c] = [1, 2]", "expected list of length 3, got 2"},
+		{"var [a, b] = [1, 2, 3]", "expected list of length 2, got 3"},
+		{"val [a, b, ...rest] = [1]", "expected list of at least 2 elements, got 1"},
+	}
+	for _, tt := range tests {
+		result := evalWithEnv(t, object.NewRootEnvironment(4), tt.src)
+		if result == nil || result.Type() != object.ERROR_OBJ {
+			t.Fatalf("%q: expected an error, got %v", tt.src, result)
+		}
+		if !strings.Contains(result.Inspect(), tt.want) {
+			t.Fatalf("%q: expected %q in error, got %s", tt.src, tt.want, result.Inspect())
+		}
+	}
+
+	result := evalWithEnv(t, object.NewRootEnvironment(4), "val [a, b, c] = [1, 2, 3]\nc")
+	if num, ok := result.(*object.Number); !ok || num.Value.ToInt() != 3 {
+		t.Fatalf("expected exact-length destructuring to bind c=3, got %v", result)
+	}
+}
+
+func TestReprRoundTrips(t *testing.T) {
+	tests := []string{
+		"nil",
+		"true",
+		"false",
+		"42",
+		"-2.5",
+		"1 / 3",
+		"1e30",
+		"0.0000001",
+		`"plain"`,
+		`"quote \" slash \\ brace \{{x}} tab \t line \n bell \7 ünï"`,
+		`0x"00ff10"`,
+		":sym",
+		`:"with space"`,
+		"[]",
+		`[1, "two", [:three, nil], {}]`,
+		`{a: 1, "b": [2], 3: {c: true}, :if: 0x"ff", [[1, 2]]: :pair, :"x y": nil}`,
+	}
+
+	for _, src := range tests {
+		result := evalWithEnv(t, object.NewRootEnvironment(4), "repr("+src+")")
+		repr, ok := result.(*object.String)
+		if !ok {
+			t.Fatalf("repr(%s): expected a string, got %v", src, result)
+		}
+
+		p := parser.New(lexer.New(repr.Value), "repr.slug", repr.Value)
+		p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("repr(%s) = %s does not parse: %v", src, repr.Value, p.Errors())
+		}
+
+		want := evalWithEnv(t, object.NewRootEnvironment(4), src)
+		got := evalWithEnv(t, object.NewRootEnvironment(4), repr.Value)
+		if !(&Task{}).objectsEqual(got, want) {
+			t.Errorf("repr(%s) = %s evaluates to %s", src, repr.Value, got.Inspect())
+		}
+	}
+
+	opaque := map[string]string{
+		"fn(x) { x }":  "<function>",
+		"println":      "<foreign>",
+		"[fn() { 1 }]": "[<function>]",
+		"{f: println}": "{f: <foreign>}",
+	}
+	for src, want := range opaque {
+		result := evalWithEnv(t, object.NewRootEnvironment(4), "repr("+src+")")
+		if repr, ok := result.(*object.String); !ok || repr.Value != want {
+			t.Errorf("repr(%s): expected %q, got %v", src, want, result)
+		}
+	}
+}
+
+func TestCallingMissingMapEntryNamesIt(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"val m = {f: fn() { 1 }}\nm.missing(1)", "not a function: m.missing is nil"},
+		{"val m = {f: fn() { 1 }}\nm[\"missing\"]()", `not a function: m["missing"] is nil`},
+		{"val m = {inner: {}}\nm.inner.missing()", "not a function: m.inner.missing is nil"},
+		{"val m = {f: fn() { 1 }}\nval call = fn() { m?.missing() }\ncall()", "not a function: m?.missing is nil"},
+		{"[nil][0]()", "not a function: NIL"},
+		{"val n = 5\nn()", "not a function: NUMBER"},
+	}
+	for _, tt := range tests {
+		result := evalWithEnv(t, object.NewRootEnvironment(4), tt.src)
+		if result == nil || result.Type() != object.ERROR_OBJ {
+			t.Fatalf("%q: expected an error, got %v", tt.src, result)
+		}
+		if !strings.Contains(result.Inspect(), tt.want) {
+			t.Errorf("%q: expected %q in error, got %s", tt.src, tt.want, result.Inspect())
+		}
+	}
+}
+
+func TestOptionalChainingOnlyGuardsNil(t *testing.T) {
+	result := evalWithEnv(t, object.NewRootEnvironment(4), "val m = {a: nil}\nm?.a?.b?.c")
+	if result != object.NIL {
+		t.Fatalf("expected nil from a nil link, got %v", result)
+	}
+
+	result = evalWithEnv(t, object.NewRootEnvironment(4), "val n = 5\nn?.a")
+	if result == nil || result.Type() != object.ERROR_OBJ {
+		t.Fatalf("expected an error indexing a number, got %v", result)
+	}
+	if !strings.Contains(result.Inspect(), "index operator not supported: NUMBER") {
+		t.Fatalf("expected the normal index error, got %s", result.Inspect())
+	}
+}
+
+func TestCompoundAssignmentWalksEnclosingScopes(t *testing.T) {
+	src := "var c = 0\nif (true) { if (true) { c += 2 } }\nc"
+	result := evalWithEnv(t, object.NewRootEnvironment(4), src)
+	if num, ok := result.(*object.Number); !ok || num.Value.ToInt() != 2 {
+		t.Fatalf("expected nested += to update the outer var, got %v", result)
+	}
+
+	result = evalWithEnv(t, object.NewRootEnvironment(4), "val c = 0\nif (true) { c += 1 }")
+	if result == nil || result.Type() != object.ERROR_OBJ {
+		t.Fatalf("expected an error assigning to an outer val, got %v", result)
+	}
+	if !strings.Contains(result.Inspect(), "value is immutible") {
+		t.Fatalf("expected an immutability error, got %s", result.Inspect())
+	}
+}
+
+func TestDivisionByZeroIsAnError(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 / 0", "division by zero: 1 / 0"},
+		{"1 % 0", "division by zero: 1 % 0"},
+		{"var x = 2.5\nx /= 0.0", "division by zero: 2.5 / 0"},
+	}
+	for _, tt := range tests {
+		result := evalWithEnv(t, object.NewRootEnvironment(4), tt.src)
+		if result == nil || result.Type() != object.ERROR_OBJ {
+			t.Fatalf("%q: expected an error, got %v", tt.src, result)
+		}
+		if !strings.Contains(result.Inspect(), tt.want) {
+			t.Fatalf("%q: expected %q in error, got %s", tt.src, tt.want, result.Inspect())
+		}
+	}
+}
+
+func TestNumericOverflowIsAnError(t *testing.T) {
+	result := evalWithEnv(t, object.NewRootEnvironment(4), "1e120 * 1e120")
+	if result == nil || result.Type() != object.ERROR_OBJ {
+		t.Fatalf("expected an overflow error, got %v", result)
+	}
+	if !strings.Contains(result.Inspect(), "numeric overflow: 1e120 * 1e120") {
+		t.Fatalf("expected a numeric overflow error, got %s", result.Inspect())
+	}
+
+	result = evalWithEnv(t, object.NewRootEnvironment(4), "1e-120 * 1e-120")
+	if num, ok := result.(*object.Number); !ok || !num.Value.IsZero() {
+		t.Fatalf("expected underflow to truncate to zero, got %v", result)
+	}
+}
+
+func TestTimeItRespectsSandbox(t *testing.T) {
+	result := evalWithEnv(t, object.NewRootEnvironment(4), "timeIt(fn() { 42 })")
+	list, ok := result.(*object.List)
+	if !ok || len(list.Elements) != 2 {
+		t.Fatalf("expected [result, nanos], got %v", result)
+	}
+	if num, ok := list.Elements[0].(*object.Number); !ok || num.Value.ToInt() != 42 {
+		t.Fatalf("expected the function result first, got %s", list.Elements[0].Inspect())
+	}
+	if nanos, ok := list.Elements[1].(*object.Number); !ok || nanos.Value.Lt(dec64.ZERO) {
+		t.Fatalf("expected non-negative nanos, got %s", list.Elements[1].Inspect())
+	}
+
+	rt := NewRuntime(util.Configuration{DefaultLimit: 4, AllowedBuiltins: []string{"len"}})
+	result = evalWithRuntime(t, rt, object.NewRootEnvironment(4), "timeIt(fn() { 42 })")
+	errObj, ok := result.(*object.Error)
+	if !ok || !strings.Contains(errObj.Message, "is not allowed by the sandbox policy") {
+		t.Fatalf("expected timeIt to be denied by the sandbox, got %s", result.Inspect())
+	}
+}
+
+func TestReturnRecurInMatchArmKeepsStackFlat(t *testing.T) {
+	env := object.NewRootEnvironment(4)
+	maxDepth := 0
+	_, err := env.DefineForeign("probe", func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+		maxDepth = max(maxDepth, len(ctx.(*Task).callStack))
+		return ctx.Nil()
+	})
+	if err != nil {
+		t.Fatalf("DefineForeign failed: %v", err)
+	}
+
+	src := `val loop = fn(n, acc) {
+	match n {
+		0 => { return acc }
+		_ => {
+			probe()
+			return recur(n - 1, acc + 2)
+		}
+	}
+}
+loop(100000, 0)`
+	result := evalWithEnv(t, env, src)
+
+	num, ok := result.(*object.Number)
+	if !ok || num.Value.ToInt() != 200000 {
+		t.Fatalf("expected 200000, got %s", result.Inspect())
+	}
+	if maxDepth > 2 {
+		t.Fatalf("expected return recur(...) to reuse the frame, call stack reached %d", maxDepth)
+	}
+}
+
+func TestRuntimeErrorColumnCountsRunes(t *testing.T) {
+	result := evalWithEnv(t, object.NewRootEnvironment(4), `val s = "héllo wörld"; nope`)
+	if result == nil || result.Type() != object.ERROR_OBJ {
+		t.Fatalf("expected an error, got %v", result)
+	}
+	if !strings.Contains(result.Inspect(), "--> test.slug:1:24") {
+		t.Fatalf("expected the column to count runes, got %s", result.Inspect())
+	}
+}
+
+const fibSrc = `
+val fib = fn(n) {
+	if (n < 2) { n } else { fib(n - 1) + fib(n - 2) }
+}
+val countDown = fn(n, acc) {
+	if (n == 0) { acc } else { recur(n - 1, acc + -1) }
+}
+val pick = fn(n) {
+	if (!(n > 3) && true) { "small" } else { "big" }
+}
+[fib(15), countDown(2000, 0), pick(2), pick(5), [1, 2, 3][1:]]
+`
+
+func TestCompiledBodiesMatchTreeWalker(t *testing.T) {
+	interpreted := NewRuntime(util.Configuration{DefaultLimit: 4, MaxSteps: 10_000_000})
+	interpreted.interpretOnly = true
+	compiled := NewRuntime(util.Configuration{DefaultLimit: 4, MaxSteps: 10_000_000})
+
+	want := evalWithRuntime(t, interpreted, object.NewRootEnvironment(4), fibSrc)
+	got := evalWithRuntime(t, compiled, object.NewRootEnvironment(4), fibSrc)
+
+	if want.Inspect() != "[610, -2000, small, big, [2, 3]]" {
+		t.Fatalf("unexpected tree walker result: %s", want.Inspect())
+	}
+	if got.Inspect() != want.Inspect() {
+		t.Fatalf("compiled result %s, tree walker %s", got.Inspect(), want.Inspect())
+	}
+	if compiled.Steps() != interpreted.Steps() {
+		t.Fatalf("compiled run took %d steps, tree walker %d", compiled.Steps(), interpreted.Steps())
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	src := `val fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }
+fib(18)`
+	program := parser.New(lexer.New(src), "bench.slug", src).ParseProgram()
+
+	for _, mode := range []struct {
+		name          string
+		interpretOnly bool
+	}{
+		{"tree-walk", true},
+		{"compiled", false},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rt := NewRuntime(util.Configuration{DefaultLimit: 4})
+				rt.interpretOnly = mode.interpretOnly
+				env := object.NewRootEnvironment(4)
+				env.Path = "bench.slug"
+				env.Src = src
+				task := &Task{Runtime: rt}
+				task.PushNurseryScope(&NurseryScope{Limit: make(chan struct{}, 4)})
+				task.PushEnv(env)
+				task.PopEnv(task.Eval(program))
+			}
+		})
+	}
+}
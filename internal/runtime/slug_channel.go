@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"slug/internal/dec64"
 	"slug/internal/object"
 )
 
@@ -47,3 +48,102 @@ func fnChannelClose() *object.Foreign {
 		},
 	}
 }
+
+func fnChannelAtomicCounter() *object.Foreign {
+	return &object.Foreign{
+		Name: "atomicCounter",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			c := &object.Counter{}
+			if len(args) == 1 {
+				num, ok := args[0].(*object.Number)
+				if !ok {
+					return ctx.NewError("counter initial value must be a number, got %s", args[0].Type())
+				}
+				c.Store(num.Value.ToInt64())
+			}
+			return c
+		},
+	}
+}
+
+// counterArguments checks the counter and the optional numeric argument shared by
+// the counter foreigns, defaulting the number to def.
+func counterArguments(ctx object.EvaluatorContext, fnName string, args []object.Object, def int64) (*object.Counter, int64, object.Object) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, 0, ctx.NewError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	c, ok := args[0].(*object.Counter)
+	if !ok {
+		return nil, 0, ctx.NewError("first argument to %s must be a counter, got %s", fnName, args[0].Type())
+	}
+	if len(args) == 1 {
+		return c, def, nil
+	}
+	num, ok := args[1].(*object.Number)
+	if !ok {
+		return nil, 0, ctx.NewError("second argument to %s must be a number, got %s", fnName, args[1].Type())
+	}
+	return c, num.Value.ToInt64(), nil
+}
+
+func fnChannelIncr() *object.Foreign {
+	return &object.Foreign{
+		Name: "incr",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			c, by, errObj := counterArguments(ctx, "incr", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Number{Value: dec64.FromInt64(c.Add(by))}
+		},
+	}
+}
+
+func fnChannelDecr() *object.Foreign {
+	return &object.Foreign{
+		Name: "decr",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			c, by, errObj := counterArguments(ctx, "decr", args, 1)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Number{Value: dec64.FromInt64(c.Add(-by))}
+		},
+	}
+}
+
+func fnChannelCounterGet() *object.Foreign {
+	return &object.Foreign{
+		Name: "counterGet",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			c, _, errObj := counterArguments(ctx, "counterGet", args, 0)
+			if errObj != nil {
+				return errObj
+			}
+			return &object.Number{Value: dec64.FromInt64(c.Load())}
+		},
+	}
+}
+
+func fnChannelCounterSet() *object.Foreign {
+	return &object.Foreign{
+		Name: "counterSet",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			c, v, errObj := counterArguments(ctx, "counterSet", args, 0)
+			if errObj != nil {
+				return errObj
+			}
+			c.Store(v)
+			return c
+		},
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"slug/internal/util"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -132,6 +133,12 @@ func fnBuiltinLen() *object.Foreign {
 				return &object.Number{Value: dec64.FromInt(utf8.RuneCountInString(arg.Value))}
 			case *object.Bytes:
 				return &object.Number{Value: dec64.FromInt(len(arg.Value))}
+			case *object.Array:
+				return &object.Number{Value: dec64.FromInt(len(arg.Elements))}
+			case *object.Dict:
+				return &object.Number{Value: dec64.FromInt(arg.Len())}
+			case *object.Set:
+				return &object.Number{Value: dec64.FromInt(arg.Len())}
 			default:
 				return ctx.NewError("argument to `len` not supported, got %s",
 					args[0].Type())
@@ -140,18 +147,24 @@ func fnBuiltinLen() *object.Foreign {
 	}
 }
 
+func fnBuiltinRepr() *object.Foreign {
+	return &object.Foreign{
+		Name: "repr",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			return &object.String{Value: object.Repr(args[0])}
+		},
+	}
+}
+
 func fnBuiltinPrint() *object.Foreign {
 	return &object.Foreign{
 		Name: "print",
 		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
-			var out bytes.Buffer
-			for i, arg := range args {
-				out.WriteString(arg.Inspect())
-				if i < len(args)-1 {
-					out.WriteString(" ")
-				}
-			}
-			print(out.String())
+			rt := ctx.(*Task).Runtime
+			rt.WriteOutput(joinDisplay(args, rt.Config.DisplayPrecision))
 			if len(args) > 0 {
 				return args[0]
 			}
@@ -164,14 +177,8 @@ func fnBuiltinPrintLn() *object.Foreign {
 	return &object.Foreign{
 		Name: "println",
 		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
-			var out bytes.Buffer
-			for i, arg := range args {
-				out.WriteString(arg.Inspect())
-				if i < len(args)-1 {
-					out.WriteString(" ")
-				}
-			}
-			println(out.String())
+			rt := ctx.(*Task).Runtime
+			rt.WriteOutput(joinDisplay(args, rt.Config.DisplayPrecision) + "\n")
 			if len(args) > 0 {
 				return args[0]
 			}
@@ -180,6 +187,58 @@ func fnBuiltinPrintLn() *object.Foreign {
 	}
 }
 
+// joinDisplay renders args for output, separated by single spaces. With places
+// above 0, numbers are rounded to that many decimal places.
+func joinDisplay(args []object.Object, places int) string {
+	var out bytes.Buffer
+	for i, arg := range args {
+		if places > 0 {
+			writeDisplay(&out, arg, places)
+		} else {
+			out.WriteString(arg.Inspect())
+		}
+		if i < len(args)-1 {
+			out.WriteString(" ")
+		}
+	}
+	return out.String()
+}
+
+// writeDisplay writes obj the way Inspect does, with numbers in lists and map
+// values rounded for display. The value itself is left untouched.
+func writeDisplay(out *bytes.Buffer, obj object.Object, places int) {
+	switch o := obj.(type) {
+	case *object.Number:
+		out.WriteString(o.Value.Round(places).String())
+	case *object.List:
+		out.WriteString("[")
+		for i, el := range o.Elements {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			writeDisplay(out, el, places)
+		}
+		out.WriteString("]")
+	case *object.Map:
+		if len(o.Tags) > 0 {
+			out.WriteString(obj.Inspect())
+			return
+		}
+		out.WriteString("{")
+		for i, pair := range o.OrderedPairs() {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(pair.Key.Inspect())
+			out.WriteString(": ")
+			writeDisplay(out, pair.Value, places)
+		}
+		out.WriteString("}")
+	default:
+		out.WriteString(obj.Inspect())
+	}
+}
+
 func fnBuiltinStacktrace() *object.Foreign {
 	return &object.Foreign{
 		Name: "stacktrace",
@@ -222,6 +281,61 @@ func fnBuiltinStacktrace() *object.Foreign {
 	}
 }
 
+// fnBuiltinApply calls a function with the elements of a list as its positional arguments.
+func fnBuiltinApply() *object.Foreign {
+	return &object.Foreign{
+		Name: "apply",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return ctx.NewError("wrong number of arguments to `apply`, got=%d, want=2", len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.FunctionGroup, *object.Foreign:
+			default:
+				return ctx.NewError("first argument to `apply` must be a function, got %s", args[0].Type())
+			}
+			list, ok := args[1].(*object.List)
+			if !ok {
+				return ctx.NewError("second argument to `apply` must be a list, got %s", args[1].Type())
+			}
+
+			// Copy the arguments so binding them never aliases the caller's list
+			positional := append([]object.Object{}, list.Elements...)
+			return ctx.ApplyFunction(0, "apply", args[0], positional, nil)
+		},
+	}
+}
+
+// fnBuiltinTimeIt runs a nullary function once and returns [result, nanos]. Reading the host clock
+// is a builtin so sandboxed runtimes can withhold it through Config.AllowedBuiltins.
+func fnBuiltinTimeIt() *object.Foreign {
+	return &object.Foreign{
+		Name: "timeIt",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments to `timeIt`, got=%d, want=1", len(args))
+			}
+			switch args[0].(type) {
+			case *object.Function, *object.FunctionGroup, *object.Foreign:
+			default:
+				return ctx.NewError("argument to `timeIt` must be a function, got %s", args[0].Type())
+			}
+
+			start := time.Now()
+			result := ctx.ApplyFunction(0, "timeIt", args[0], []object.Object{}, nil)
+			elapsed := time.Since(start)
+			if result != nil && result.Type() == object.ERROR_OBJ {
+				return result
+			}
+
+			return &object.List{Elements: []object.Object{
+				result,
+				&object.Number{Value: dec64.FromInt64(elapsed.Nanoseconds())},
+			}}
+		},
+	}
+}
+
 func fnBuiltinArgv() *object.Foreign {
 	return &object.Foreign{
 		Name: "argv",
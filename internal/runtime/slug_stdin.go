@@ -0,0 +1,70 @@
+package runtime
+
+import (
+	"errors"
+	"io"
+	"slug/internal/object"
+	"strings"
+)
+
+func fnStdinReadLine() *object.Foreign {
+	return &object.Foreign{
+		Name: "readLine",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+
+			rt := ctx.(*Task).Runtime
+			rt.inMu.Lock()
+			defer rt.inMu.Unlock()
+
+			line, err := rt.stdinReader().ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return ctx.NewError("failed to read from stdin: %s", err.Error())
+			}
+			if line == "" && err != nil {
+				return ctx.Nil()
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\r")
+			return &object.String{Value: line}
+		},
+	}
+}
+
+func fnStdinRead() *object.Foreign {
+	return &object.Foreign{
+		Name: "read",
+		Fn: func(ctx object.EvaluatorContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return ctx.NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			num, ok := args[0].(*object.Number)
+			if !ok {
+				return ctx.NewError("argument to `read` must be a NUMBER, got=%s", args[0].Type())
+			}
+			n := num.Value.ToInt64()
+			if n < 0 {
+				return ctx.NewError("argument to `read` must be >= 0, got=%d", n)
+			}
+
+			rt := ctx.(*Task).Runtime
+			rt.inMu.Lock()
+			defer rt.inMu.Unlock()
+
+			buf := make([]byte, n)
+			read, err := io.ReadFull(rt.stdinReader(), buf)
+			if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return ctx.NewError("failed to read from stdin: %s", err.Error())
+			}
+			if read == 0 && n > 0 {
+				return ctx.Nil()
+			}
+
+			return &object.Bytes{Value: buf[:read]}
+		},
+	}
+}
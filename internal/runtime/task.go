@@ -5,13 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"slug/internal/ast"
 	"slug/internal/dec64"
 	"slug/internal/foreign"
+	"slug/internal/lexer"
 	"slug/internal/object"
+	"slug/internal/token"
 	"slug/internal/util"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -34,6 +39,7 @@ type Task struct {
 	Done         chan struct{} // Closed when the task is finished
 	Observed     bool
 	IsFinished   bool
+	cancelled    bool // settled by Cancel while its goroutine was still running
 	mu           sync.Mutex
 
 	envStack     []*object.Environment // Environment stack encapsulated in an evaluator struct
@@ -49,6 +55,19 @@ func (e *Task) NextHandleID() int64 {
 	return e.Runtime.NextHandleID()
 }
 
+func (e *Task) RandomBytes(p []byte) {
+	e.Runtime.RandomBytes(p)
+}
+
+func (e *Task) CompileRegex(pattern string) (*regexp.Regexp, error) {
+	return e.Runtime.CompileRegex(pattern)
+}
+
+// ObjectsEqual compares two values the way `==` does for lists, maps and bytes.
+func (e *Task) ObjectsEqual(a, b object.Object) bool {
+	return e.objectsEqual(a, b)
+}
+
 func (e *Task) GetConfiguration() util.Configuration {
 	return e.Runtime.Config
 }
@@ -135,10 +154,9 @@ func (e *Task) popNurseryScope(result object.Object) (object.Object, bool) {
 	currentScope.WaitChildren()
 
 	// If any child failed and the current result isn't already an error/return, propagate it upward.
-	if currentScope.NurseryErr != nil {
-		if result == nil || (result.Type() != object.ERROR_OBJ && result.Type() != object.RETURN_VALUE_OBJ) {
-			result = currentScope.NurseryErr
-			currentScope.NurseryErr = nil
+	if result == nil || (result.Type() != object.ERROR_OBJ && result.Type() != object.RETURN_VALUE_OBJ) {
+		if nurseryErr := currentScope.TakeErr(); nurseryErr != nil {
+			result = nurseryErr
 			nurseryInjected = true
 		}
 	}
@@ -172,6 +190,10 @@ func (e *Task) popCallFrame() {
 }
 
 func (e *Task) Eval(node ast.Node) object.Object {
+	if e.Runtime.Config.MaxSteps > 0 {
+		e.Runtime.steps.Add(1)
+	}
+
 	switch node := node.(type) {
 
 	// Statements
@@ -202,10 +224,20 @@ func (e *Task) Eval(node ast.Node) object.Object {
 		if e.isError(variable) {
 			return variable
 		}
+		variable = e.memoizeIfTagged(node.Tags, variable)
 		isExported := hasExportTag(node.Tags)
-		if _, err := e.patternMatches(node.Pattern, variable, false, isExported, false, e.CurrentEnv()); err != nil {
+		matched, err := e.patternMatches(node.Pattern, variable, false, isExported, false, e.CurrentEnv())
+		if err != nil {
 			return e.newErrorWithPos(node.Token.Position, err.Error())
 		}
+		if errObj := e.defineOperatorIfTagged(node.Token.Position, node.Tags, variable, isExported); errObj != nil {
+			return errObj
+		}
+		if !matched {
+			if msg, ok := listLengthMismatch(node.Pattern, variable); ok {
+				return e.newErrorWithPos(node.Token.Position, msg)
+			}
+		}
 		e.applyDocIfPresent(node.Pattern, node.Doc, node.HasDoc)
 		return e.applyTagsIfPresent(node.Tags, variable)
 
@@ -214,10 +246,20 @@ func (e *Task) Eval(node ast.Node) object.Object {
 		if e.isError(value) {
 			return value
 		}
+		value = e.memoizeIfTagged(node.Tags, value)
 		isExported := hasExportTag(node.Tags)
-		if _, err := e.patternMatches(node.Pattern, value, true, isExported, false, e.CurrentEnv()); err != nil {
+		matched, err := e.patternMatches(node.Pattern, value, true, isExported, false, e.CurrentEnv())
+		if err != nil {
 			return e.newErrorWithPos(node.Token.Position, err.Error())
 		}
+		if errObj := e.defineOperatorIfTagged(node.Token.Position, node.Tags, value, isExported); errObj != nil {
+			return errObj
+		}
+		if !matched {
+			if msg, ok := listLengthMismatch(node.Pattern, value); ok {
+				return e.newErrorWithPos(node.Token.Position, msg)
+			}
+		}
 		e.applyDocIfPresent(node.Pattern, node.Doc, node.HasDoc)
 		return e.applyTagsIfPresent(node.Tags, value)
 
@@ -281,7 +323,7 @@ func (e *Task) Eval(node ast.Node) object.Object {
 		}
 
 		// Short circuit for boolean operations
-		if node.Operator == "&&" || node.Operator == "||" {
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
 			return e.evalShortCircuitInfixExpression(left, node)
 		}
 
@@ -290,7 +332,13 @@ func (e *Task) Eval(node ast.Node) object.Object {
 			return right
 		}
 
-		return e.evalInfixExpression(node.Operator, left, right)
+		if node.Token.Type == token.CUSTOM_OPERATOR {
+			return e.evalCustomOperator(node.Token.Position, node.Operator, left, right)
+		}
+		return e.evalInfixExpression(node.Token.Position, node.Operator, left, right)
+
+	case *ast.ComparisonChain:
+		return e.evalComparisonChain(node)
 
 	case *ast.IfExpression:
 		return e.evalIfExpression(node)
@@ -309,13 +357,21 @@ func (e *Task) Eval(node ast.Node) object.Object {
 			Body:        body,
 			Signature:   node.Signature,
 			HasTailCall: node.HasTailCall,
+			Compiled:    e.Runtime.compiledBody(body),
 		}
 
 	case *ast.CallExpression:
+		if errObj := e.checkLimits(node.Token.Position); errObj != nil {
+			return errObj
+		}
+
 		function := e.Eval(node.Function)
 		if e.isError(function) {
 			return function
 		}
+		if function == object.NIL {
+			return e.callNilError(node)
+		}
 
 		positional, named, err := e.evalCallArguments(node.Token.Position, node.Arguments)
 		if err != nil {
@@ -343,6 +399,10 @@ func (e *Task) Eval(node ast.Node) object.Object {
 		return e.ApplyFunction(node.Token.Position, node.Token.Literal, function, positional, named)
 
 	case *ast.RecurExpression:
+		if errObj := e.checkLimits(node.Token.Position); errObj != nil {
+			return errObj
+		}
+
 		// Evaluate arguments (respecting spread and named args, same as call)
 		positional, named, err := e.evalCallArguments(node.Token.Position, node.Arguments)
 		if err != nil {
@@ -369,11 +429,7 @@ func (e *Task) Eval(node ast.Node) object.Object {
 		}
 
 	case *ast.ListLiteral:
-		elements := e.evalExpressions(node.Elements)
-		if len(elements) == 1 && e.isError(elements[0]) {
-			return elements[0]
-		}
-		return &object.List{Elements: elements}
+		return e.evalListLiteral(node)
 
 	case *ast.StructSchemaExpression:
 		return e.evalStructSchemaExpression(node)
@@ -387,6 +443,9 @@ func (e *Task) Eval(node ast.Node) object.Object {
 		if e.isError(left) {
 			return left
 		}
+		if node.Optional && left == object.NIL {
+			return object.NIL
+		}
 		index := e.Eval(node.Index)
 		if e.isError(index) {
 			return index
@@ -545,13 +604,28 @@ func (e *Task) evalPrefixExpression(operator string, right object.Object) object
 	}
 }
 
+// evalCustomOperator calls the function an @operator tag bound to operator,
+// which is looked up like any other name.
+func (e *Task) evalCustomOperator(pos int, operator string, left, right object.Object) object.Object {
+	fn, ok := e.CurrentEnv().Get(operator)
+	if !ok {
+		return e.newErrorfWithPos(pos, "operator not defined: %s", operator)
+	}
+	fn = e.resolveValue(pos, fn)
+	if e.isError(fn) {
+		return fn
+	}
+	return e.ApplyFunction(pos, operator, fn, []object.Object{left, right}, nil)
+}
+
 func (e *Task) evalInfixExpression(
+	pos int,
 	operator string,
 	left, right object.Object,
 ) object.Object {
 	switch {
 	case left.Type() == object.NUMBER_OBJ && right.Type() == object.NUMBER_OBJ:
-		return e.evalNumberInfixExpression(operator, left, right)
+		return e.evalNumberInfixExpression(pos, operator, left, right)
 
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return e.evalStringInfixExpression(operator, left, right)
@@ -588,6 +662,13 @@ func (e *Task) evalInfixExpression(
 	case operator == "^" && right.Type() == object.BYTE_OBJ && left.Type() == object.NUMBER_OBJ:
 		return e.doOp(left, right, XorBytes)
 
+	case (operator == "==" || operator == "!=") && isAnonStruct(left) && isAnonStruct(right):
+		return e.NativeBoolToBooleanObject(e.objectsEqual(left, right) == (operator == "=="))
+	case (operator == "==" || operator == "!=") && left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return e.NativeBoolToBooleanObject(e.objectsEqual(left, right) == (operator == "=="))
+	case (operator == "==" || operator == "!=") && isFunctionValue(left) && isFunctionValue(right):
+		return e.NativeBoolToBooleanObject(object.SameFunction(left, right) == (operator == "=="))
+
 	case operator == "==":
 		return e.NativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -658,6 +739,10 @@ func (e *Task) evalShortCircuitInfixExpression(left object.Object, node *ast.Inf
 		if e.isError(right) {
 			return right
 		}
+		// A `recur` on the right is in tail position, hand it back to the trampoline
+		if _, ok := right.(*object.TailCall); ok {
+			return right
+		}
 		if e.isTruthy(right) {
 			return object.TRUE
 		}
@@ -673,16 +758,48 @@ func (e *Task) evalShortCircuitInfixExpression(left object.Object, node *ast.Inf
 		if e.isError(right) {
 			return right
 		}
+		// A `recur` on the right is in tail position, hand it back to the trampoline
+		if _, ok := right.(*object.TailCall); ok {
+			return right
+		}
 		if e.isTruthy(right) {
 			return object.TRUE
 		}
 		return object.FALSE
 
+	case "??":
+		// If left is not nil, return it without evaluating right
+		if left != object.NIL {
+			return left
+		}
+		return e.Eval(node.Right)
+
 	default:
 		return e.newErrorfWithPos(node.Token.Position, "unknown operator for short-circuit evaluation: %s", node.Operator)
 	}
 }
 
+// evalComparisonChain stops at the first pair that compares false, so later
+// operands are only evaluated while the chain still holds.
+func (e *Task) evalComparisonChain(node *ast.ComparisonChain) object.Object {
+	left := e.Eval(node.Operands[0])
+	if e.isError(left) {
+		return left
+	}
+	for i, operator := range node.Operators {
+		right := e.Eval(node.Operands[i+1])
+		if e.isError(right) {
+			return right
+		}
+		result := e.evalInfixExpression(node.Token.Position, operator, left, right)
+		if e.isError(result) || !e.isTruthy(result) {
+			return result
+		}
+		left = right
+	}
+	return object.TRUE
+}
+
 func (e *Task) evalBooleanInfixExpression(
 	operator string,
 	left, right object.Object,
@@ -706,23 +823,29 @@ func (e *Task) evalBooleanInfixExpression(
 }
 
 func (e *Task) evalNumberInfixExpression(
+	pos int,
 	operator string,
 	left, right object.Object,
 ) object.Object {
 	leftVal := left.(*object.Number).Value
 	rightVal := right.(*object.Number).Value
 
+	// dec64 answers NaN for a zero divisor, slug reports it instead of letting NaN spread
+	if (operator == "/" || operator == "%") && rightVal.IsZero() {
+		return e.newErrorfWithPos(pos, "division by zero: %s %s 0", leftVal.String(), operator)
+	}
+
 	switch operator {
 	case "+":
-		return &object.Number{Value: leftVal.Add(rightVal)}
+		return e.checkedNumber(pos, operator, leftVal, rightVal, leftVal.Add(rightVal))
 	case "-":
-		return &object.Number{Value: leftVal.Sub(rightVal)}
+		return e.checkedNumber(pos, operator, leftVal, rightVal, leftVal.Sub(rightVal))
 	case "*":
-		return &object.Number{Value: leftVal.Mul(rightVal)}
+		return e.checkedNumber(pos, operator, leftVal, rightVal, leftVal.Mul(rightVal))
 	case "/":
-		return &object.Number{Value: leftVal.Div(rightVal, precision, roundingStrategy)}
+		return e.checkedNumber(pos, operator, leftVal, rightVal, leftVal.Div(rightVal, precision, roundingStrategy))
 	case "%":
-		return &object.Number{Value: leftVal.Mod(rightVal)}
+		return e.checkedNumber(pos, operator, leftVal, rightVal, leftVal.Mod(rightVal))
 	case "&":
 		return &object.Number{Value: leftVal.And(rightVal)}
 	case "|":
@@ -751,6 +874,15 @@ func (e *Task) evalNumberInfixExpression(
 	}
 }
 
+// checkedNumber wraps an arithmetic result, reporting an error when dec64 could not represent it
+// (it answers NaN once the exponent passes 10^127) rather than letting NaN spread.
+func (e *Task) checkedNumber(pos int, operator string, left, right, result dec64.Dec64) object.Object {
+	if result.IsNaN() && !left.IsNaN() && !right.IsNaN() {
+		return e.newErrorfWithPos(pos, "numeric overflow: %s %s %s", left.String(), operator, right.String())
+	}
+	return &object.Number{Value: result}
+}
+
 func (e *Task) evalStringInfixExpression(
 	operator string,
 	left, right object.Object,
@@ -927,6 +1059,18 @@ func (e *Task) evalBytesInfixExpression(
 			return &object.Bytes{Value: newBytes}
 		}
 		return &object.Bytes{Value: []byte{}}
+	case "<", "<=", ">", ">=":
+		cmp := bytes.Compare(left.(*object.Bytes).Value, right.(*object.Bytes).Value)
+		switch operator {
+		case "<":
+			return e.NativeBoolToBooleanObject(cmp < 0)
+		case "<=":
+			return e.NativeBoolToBooleanObject(cmp <= 0)
+		case ">":
+			return e.NativeBoolToBooleanObject(cmp > 0)
+		default:
+			return e.NativeBoolToBooleanObject(cmp >= 0)
+		}
 	default:
 		return e.newErrorf("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
@@ -989,6 +1133,9 @@ func (e *Task) evalIdentifier(
 ) object.Object {
 
 	if builtin, ok := e.Runtime.Builtins[node.Value]; ok {
+		if !e.Runtime.BuiltinAllowed(node.Value) {
+			return e.newErrorfWithPos(node.Token.Position, "builtin `%s` is not allowed by the sandbox policy", node.Value)
+		}
 		return builtin
 	}
 
@@ -1049,24 +1196,56 @@ func (e *Task) newErrorfWithPos(pos int, format string, a ...interface{}) *objec
 	return e.newErrorWithPos(pos, m)
 }
 
-func (e *Task) newErrorWithPos(pos int, m string) *object.Error {
+// checkLimits reports an error once the runtime has used up Config.MaxSteps or passed its deadline.
+// It is checked at calls and recur since every unbounded computation has to pass through one of
+// them; spawned tasks share the runtime so they stop too and their nursery cancels the rest.
+func (e *Task) checkLimits(pos int) *object.Error {
+	maxSteps := e.Runtime.Config.MaxSteps
+	if maxSteps > 0 && e.Runtime.steps.Load() > maxSteps {
+		return e.newErrorfWithPos(pos, "computation budget exceeded: more than %d steps", maxSteps)
+	}
+	if !e.Runtime.deadline.IsZero() && time.Now().After(e.Runtime.deadline) {
+		return e.newErrorWithPos(pos, "deadline exceeded")
+	}
+	return nil
+}
 
-	if pos == 0 {
-		return &object.Error{Message: m}
+// warnIfDeprecated logs a warning the first time a function tagged @deprecated("reason") is called.
+func (e *Task) warnIfDeprecated(pos int, fnName string, fn *object.Function) {
+	params, ok := fn.GetTagParams(object.DEPRECATED_TAG)
+	if !ok {
+		return
+	}
+	if _, seen := e.Runtime.deprecatedSeen.LoadOrStore(fn, struct{}{}); seen {
+		return
 	}
 
-	env := e.CurrentEnv()
+	// calls through an expression carry the '(' token rather than a name
+	msg := "call to deprecated function"
+	if fnName != "" && fnName != "(" {
+		msg = fmt.Sprintf("call to deprecated function `%s`", fnName)
+	}
+	if len(params.Elements) > 0 {
+		msg += ": " + params.Elements[0].Inspect()
+	}
 
+	env := e.CurrentEnv()
 	line, col := util.GetLineAndColumn(env.Src, pos)
+	slog.Warn(msg,
+		slog.String("file", env.Path),
+		slog.Int("line", line),
+		slog.Int("column", col))
+	fmt.Fprintf(e.Runtime.Stderr, "Warning: %s\n    --> %s:%d:%d\n", msg, env.Path, line, col)
+}
 
-	var errorMsg bytes.Buffer
-	errorMsg.WriteString(fmt.Sprintf("Error: %s\n", m))
-	errorMsg.WriteString(fmt.Sprintf("    --> %s:%d:%d\n", env.Path, line, col))
+func (e *Task) newErrorWithPos(pos int, m string) *object.Error {
 
-	lines := util.GetContextLines(env.Src, line, col)
-	errorMsg.WriteString(lines)
+	if pos == 0 {
+		return &object.Error{Message: m}
+	}
 
-	return &object.Error{Message: errorMsg.String()}
+	env := e.CurrentEnv()
+	return &object.Error{Message: util.FormatDiagnostic(env.Src, env.Path, pos, "Error: "+m)}
 }
 
 func (e *Task) newErrorf(format string, a ...interface{}) *object.Error {
@@ -1081,20 +1260,29 @@ func (e *Task) isError(obj object.Object) bool {
 	return false
 }
 
-func (e *Task) evalExpressions(
-	exps []ast.Expression,
-) []object.Object {
-	var result []object.Object
-
-	for _, err := range exps {
-		evaluated := e.Eval(err)
+// evalListLiteral evaluates list elements in order, expanding `...xs` spreads in place.
+func (e *Task) evalListLiteral(node *ast.ListLiteral) object.Object {
+	elements := make([]object.Object, 0, len(node.Elements))
+	for _, el := range node.Elements {
+		if spread, ok := el.(*ast.SpreadExpression); ok {
+			spreadValue := e.Eval(spread.Value)
+			if e.isError(spreadValue) {
+				return spreadValue
+			}
+			list, ok := spreadValue.(*object.List)
+			if !ok {
+				return e.newErrorfWithPos(spread.Token.Position, "spread operator can only be used on lists, got %s", spreadValue.Type())
+			}
+			elements = append(elements, list.Elements...)
+			continue
+		}
+		evaluated := e.Eval(el)
 		if e.isError(evaluated) {
-			return []object.Object{evaluated}
+			return evaluated
 		}
-		result = append(result, evaluated)
+		elements = append(elements, evaluated)
 	}
-
-	return result
+	return &object.List{Elements: elements}
 }
 
 type boundArguments struct {
@@ -1297,6 +1485,10 @@ func (e *Task) ApplyFunction(pos int, fnName string, fnObj object.Object, positi
 
 	case *object.Function:
 
+		if fn.Tags != nil {
+			e.warnIfDeprecated(pos, fnName, fn)
+		}
+
 		// Track current function for `recur`
 		e.pushCallFrame(fnName, fn)
 		defer e.popCallFrame()
@@ -1314,7 +1506,7 @@ func (e *Task) ApplyFunction(pos int, fnName string, fnObj object.Object, positi
 		e.PushEnv(blockEnv)
 
 		for {
-			result = e.evalBlockStatementWithinEnv(fn.Body)
+			result = e.evalFunctionBody(fn)
 
 			_, ok := result.(*object.Error)
 			if ok {
@@ -1322,11 +1514,11 @@ func (e *Task) ApplyFunction(pos int, fnName string, fnObj object.Object, positi
 			}
 
 			nurseryScope := e.currentNurseryScope()
-			if nurseryScope.NurseryErr != nil {
-				_, ok := nurseryScope.NurseryErr.(*object.Error)
+			if nurseryErr := nurseryScope.Err(); nurseryErr != nil {
+				_, ok := nurseryErr.(*object.Error)
 				if ok {
 					// if the current nursery is erroring break out
-					result = nurseryScope.NurseryErr
+					result = nurseryErr
 					//nurseryScope.NurseryErr = nil
 					break
 				}
@@ -1379,25 +1571,16 @@ func (e *Task) ApplyFunction(pos int, fnName string, fnObj object.Object, positi
 
 	case *object.Foreign:
 		var result object.Object
-		bound, errObj := e.bindArguments(pos, fn, fn.Parameters, positional, named)
+		callArgs, errObj := e.foreignCallArguments(pos, fn, positional, named)
 		if errObj != nil {
 			return errObj
 		}
-		callArgs := bound.Values
-		if fn.Parameters != nil && len(fn.Parameters) > 0 && fn.Parameters[len(fn.Parameters)-1].IsVariadic {
-			variadicIndex := len(fn.Parameters) - 1
-			callArgs = append([]object.Object{}, bound.Values[:variadicIndex]...)
-			if variadicVal, ok := bound.Values[variadicIndex].(*object.List); ok {
-				callArgs = append(callArgs, variadicVal.Elements...)
-			} else if bound.Values[variadicIndex] != nil {
-				callArgs = append(callArgs, bound.Values[variadicIndex])
-			}
-		}
 		func() {
+			// A panic in native code fails the call like a returned error would, so the
+			// caller's defers still run and onerror can see it.
 			defer func() {
 				if r := recover(); r != nil {
-					println(r.(error).Error())
-					result = e.newErrorfWithPos(pos, "error calling foreign function '%s'", fn.Name)
+					result = e.newErrorfWithPos(pos, "foreign function '%s' panicked: %v", fn.Name, r)
 				}
 			}()
 			result = fn.Fn(e, callArgs...)
@@ -1423,6 +1606,78 @@ func (e *Task) ApplyFunction(pos int, fnName string, fnObj object.Object, positi
 	}
 }
 
+// callNilError reports a call through nil, most often a missing map entry called
+// like a method, naming the callee when it is a name or a chain of keys.
+func (e *Task) callNilError(node *ast.CallExpression) object.Object {
+	if name := calleeName(node.Function); name != "" {
+		return e.newErrorfWithPos(node.Token.Position, "not a function: %s is nil", name)
+	}
+	return e.newErrorfWithPos(node.Token.Position, "not a function: %s", object.NIL.Type())
+}
+
+// calleeName renders `obj.method` and `obj["method"]` callees as written, or ""
+// for any other expression.
+func calleeName(expr ast.Expression) string {
+	switch n := expr.(type) {
+	case *ast.Identifier:
+		return n.Value
+	case *ast.IndexExpression:
+		left := calleeName(n.Left)
+		if left == "" {
+			return ""
+		}
+		switch key := n.Index.(type) {
+		case *ast.SymbolLiteral:
+			if n.Optional {
+				return left + "?." + key.Value
+			}
+			return left + "." + key.Value
+		case *ast.StringLiteral:
+			return fmt.Sprintf("%s[%q]", left, key.Value)
+		}
+	}
+	return ""
+}
+
+// foreignCallArguments binds the arguments of a foreign call and expands a
+// variadic last parameter into individual arguments. A RawArgs foreign gets
+// the positional list and a map of the named arguments instead.
+func (e *Task) foreignCallArguments(
+	pos int,
+	fn *object.Foreign,
+	positional []object.Object,
+	named map[string]object.Object,
+) ([]object.Object, object.Object) {
+	if fn.RawArgs {
+		names := make([]string, 0, len(named))
+		for name := range named {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		namedMap := &object.Map{}
+		for _, name := range names {
+			namedMap.Put(object.InternSymbol(name), named[name])
+		}
+		return []object.Object{&object.List{Elements: append([]object.Object{}, positional...)}, namedMap}, nil
+	}
+
+	bound, errObj := e.bindArguments(pos, fn, fn.Parameters, positional, named)
+	if errObj != nil {
+		return nil, errObj
+	}
+	callArgs := bound.Values
+	if len(fn.Parameters) > 0 && fn.Parameters[len(fn.Parameters)-1].IsVariadic {
+		variadicIndex := len(fn.Parameters) - 1
+		callArgs = append([]object.Object{}, bound.Values[:variadicIndex]...)
+		if variadicVal, ok := bound.Values[variadicIndex].(*object.List); ok {
+			callArgs = append(callArgs, variadicVal.Elements...)
+		} else if bound.Values[variadicIndex] != nil {
+			callArgs = append(callArgs, bound.Values[variadicIndex])
+		}
+	}
+	return callArgs, nil
+}
+
 func (e *Task) extendFunctionEnv(
 	pos int,
 	fn *object.Function,
@@ -1516,29 +1771,43 @@ func (e *Task) evalDefaultParam(fnObj object.Object, expr ast.Expression) object
 func (e *Task) evalMapLiteral(
 	node *ast.MapLiteral,
 ) object.Object {
-	pairs := make(map[object.MapKey]object.MapPair)
+	result := &object.Map{Pairs: make(map[object.MapKey]object.MapPair, len(node.Pairs))}
+
+	for _, keyNode := range node.Keys {
+		if spread, ok := keyNode.(*ast.SpreadExpression); ok {
+			source := e.Eval(spread.Value)
+			if e.isError(source) {
+				return source
+			}
+			sourceMap, ok := source.(*object.Map)
+			if !ok {
+				return e.newErrorfWithPos(spread.Token.Position, "spread operator in a map literal can only be used on maps, got %s", source.Type())
+			}
+			for _, pair := range sourceMap.OrderedPairs() {
+				result.Put(pair.Key.(object.Hashable), pair.Value)
+			}
+			continue
+		}
 
-	for keyNode, valueNode := range node.Pairs {
 		key := e.Eval(keyNode)
 		if e.isError(key) {
 			return key
 		}
 
-		mapKey, ok := key.(object.Hashable)
+		mapKey, ok := object.HashableKey(key)
 		if !ok {
 			return e.newErrorfWithPos(node.Token.Position, "unusable as map key: %s", key.Type())
 		}
 
-		value := e.Eval(valueNode)
+		value := e.Eval(node.Pairs[keyNode])
 		if e.isError(value) {
 			return value
 		}
 
-		mapKeyHash := mapKey.MapKey()
-		pairs[mapKeyHash] = object.MapPair{Key: key, Value: value}
+		result.Put(mapKey, value)
 	}
 
-	return &object.Map{Pairs: pairs}
+	return result
 }
 
 func (e *Task) evalStructSchemaExpression(node *ast.StructSchemaExpression) object.Object {
@@ -1552,24 +1821,36 @@ func (e *Task) evalStructSchemaExpression(node *ast.StructSchemaExpression) obje
 		if _, exists := schema.FieldIndex[field.Name]; exists {
 			return e.newErrorfWithPos(field.Token.Position, "duplicate struct field: %s", field.Name)
 		}
-		if field.Hint != "" {
-			if _, ok := object.TypeTags[field.Hint]; !ok {
-				return e.newErrorfWithPos(field.Token.Position, "unknown struct field type hint: %s", field.Hint)
+		schemaField := object.StructSchemaField{
+			Name:    field.Name,
+			Default: field.Default,
+		}
+		for _, tag := range field.Tags {
+			switch {
+			case tag == object.NONNIL_TAG:
+				schemaField.Tags = append(schemaField.Tags, tag)
+			case schemaField.Hint != "":
+				return e.newErrorfWithPos(field.Token.Position, "struct field %s has more than one type hint", field.Name)
+			default:
+				if _, ok := object.TypeTags[tag]; !ok {
+					return e.newErrorfWithPos(field.Token.Position, "unknown struct field type hint: %s", tag)
+				}
+				schemaField.Hint = tag
 			}
 		}
 
 		schema.FieldIndex[field.Name] = len(schema.Fields)
-		schema.Fields = append(schema.Fields, object.StructSchemaField{
-			Name:    field.Name,
-			Default: field.Default,
-			Hint:    field.Hint,
-		})
+		schema.Fields = append(schema.Fields, schemaField)
 	}
 
 	return schema
 }
 
 func (e *Task) evalStructInitExpression(node *ast.StructInitExpression) object.Object {
+	if node.Schema == nil {
+		return e.evalAnonStructLiteral(node)
+	}
+
 	schemaObj := e.Eval(node.Schema)
 	if e.isError(schemaObj) {
 		return schemaObj
@@ -1608,6 +1889,14 @@ func (e *Task) evalStructInitExpression(node *ast.StructInitExpression) object.O
 			if e.isError(val) {
 				return val
 			}
+			// a zero-arg function default is a factory, called for every init so each
+			// instance gets a fresh value; @fn fields keep the function itself
+			if fn, ok := val.(*object.Function); ok && fn.Signature.Min == 0 && field.Hint != object.FUNCTION_TAG {
+				val = e.ApplyFunction(node.Token.Position, field.Name, fn, nil, nil)
+				if e.isError(val) {
+					return val
+				}
+			}
 			values[field.Name] = val
 		} else {
 			values[field.Name] = object.NIL
@@ -1624,6 +1913,34 @@ func (e *Task) evalStructInitExpression(node *ast.StructInitExpression) object.O
 	}
 }
 
+// evalAnonStructLiteral builds a struct from `#{...}` with a schema inferred from its fields.
+func (e *Task) evalAnonStructLiteral(node *ast.StructInitExpression) object.Object {
+	schema := &object.StructSchema{
+		Fields:     make([]object.StructSchemaField, 0, len(node.Fields)),
+		FieldIndex: make(map[string]int, len(node.Fields)),
+		Env:        e.CurrentEnv(),
+		Anonymous:  true,
+	}
+	values := make(map[string]object.Object, len(node.Fields))
+	for _, field := range node.Fields {
+		if _, ok := schema.FieldIndex[field.Name]; ok {
+			return e.newErrorfWithPos(field.Token.Position, "duplicate field '%s' in struct literal", field.Name)
+		}
+		val := e.Eval(field.Value)
+		if e.isError(val) {
+			return val
+		}
+		schema.FieldIndex[field.Name] = len(schema.Fields)
+		schema.Fields = append(schema.Fields, object.StructSchemaField{Name: field.Name})
+		values[field.Name] = val
+	}
+
+	return &object.StructValue{
+		Schema: schema,
+		Fields: values,
+	}
+}
+
 func (e *Task) evalStructCopyExpression(node *ast.StructCopyExpression) object.Object {
 	source := e.Eval(node.Source)
 	if e.isError(source) {
@@ -1693,14 +2010,17 @@ func (e *Task) evalStructDefault(schema *object.StructSchema, expr ast.Expressio
 
 func (e *Task) validateStructHints(pos int, schema *object.StructSchema, values map[string]object.Object) object.Object {
 	for _, field := range schema.Fields {
-		if field.Hint == "" {
-			continue
-		}
 		value := values[field.Name]
 		if value == nil {
 			value = object.NIL
 		}
 		if value.Type() == object.NIL_OBJ {
+			if field.HasTag(object.NONNIL_TAG) {
+				return e.newErrorfWithPos(pos, "struct %s field %s must not be nil", e.structSchemaName(schema), field.Name)
+			}
+			continue
+		}
+		if field.Hint == "" {
 			continue
 		}
 
@@ -1749,8 +2069,7 @@ func (e *Task) evalMatchExpression(node *ast.MatchExpression) object.Object {
 		}
 	}
 
-	// No match found
-	return object.NIL
+	return e.noMatch(node, matchValue)
 }
 
 func (e *Task) evalMatchExpressionWithValue(node *ast.MatchExpression, matchValue object.Object) object.Object {
@@ -1760,7 +2079,28 @@ func (e *Task) evalMatchExpressionWithValue(node *ast.MatchExpression, matchValu
 			return result
 		}
 	}
-	return object.NIL
+	return e.noMatch(node, matchValue)
+}
+
+// noMatch is the value of a match expression when no case matched: nil, or an
+// error under Config.StrictMatch.
+func (e *Task) noMatch(node *ast.MatchExpression, matchValue object.Object) object.Object {
+	if !e.Runtime.Config.StrictMatch {
+		return object.NIL
+	}
+	if matchValue == nil {
+		return e.newErrorWithPos(node.Token.Position, "no match case matched")
+	}
+	return e.newErrorfWithPos(node.Token.Position, "no match case matched %s", matchValue.Inspect())
+}
+
+// indexOutOfRange is the value of an index past either end of a list, string or
+// bytes: nil, or an error under Config.StrictIndex.
+func (e *Task) indexOutOfRange(pos int, kind string, idx int64, length int) object.Object {
+	if !e.Runtime.Config.StrictIndex {
+		return object.NIL
+	}
+	return e.newErrorfWithPos(pos, "index %d out of range for %s of length %d", idx, kind, length)
 }
 
 func (e *Task) evalMatchCase(matchValue object.Object, matchCase *ast.MatchCase) (result object.Object, matched bool) {
@@ -1788,10 +2128,15 @@ func (e *Task) evalMatchCase(matchValue object.Object, matchCase *ast.MatchCase)
 			return result, true
 		}
 		// Valueless match condition
-		matched = e.evaluatePatternAsCondition(matchCase.Pattern)
+		var errObj object.Object
+		matched, errObj = e.evaluatePatternAsCondition(matchCase.Pattern)
+		if errObj != nil {
+			return errObj, true
+		}
 	}
 
-	// Evaluate guard condition if pattern matches
+	// Evaluate guard condition if pattern matches. The guard runs in patternEnv, so it can refer
+	// to anything the pattern just bound.
 	if matched && matchCase.Guard != nil {
 		guardResult := e.Eval(matchCase.Guard)
 		if e.isError(guardResult) {
@@ -1864,6 +2209,27 @@ func (e *Task) patternMatches(
 		}
 		return true, nil
 
+	case *ast.StringConcatPattern:
+		// Match the literal ends and bind what is left between them
+		str, ok := value.(*object.String)
+		if !ok {
+			return false, nil
+		}
+		rest := str.Value
+		if p.Prefix != nil {
+			if !strings.HasPrefix(rest, p.Prefix.Value) {
+				return false, nil
+			}
+			rest = rest[len(p.Prefix.Value):]
+		}
+		if p.Suffix != nil {
+			if !strings.HasSuffix(rest, p.Suffix.Value) {
+				return false, nil
+			}
+			rest = rest[:len(rest)-len(p.Suffix.Value)]
+		}
+		return e.patternMatches(p.Rest, &object.String{Value: rest}, isConstant, isExport, isImport, pinEnv)
+
 	case *ast.LiteralPattern:
 		// Evaluate the literal and compare with the value
 		literalValue := e.Eval(p.Value)
@@ -1925,7 +2291,7 @@ func (e *Task) patternMatches(
 
 		if p.SelectAll {
 			// Copy all key-value pairs into current scope
-			for _, pair := range mapObj.Pairs {
+			for _, pair := range mapObj.OrderedPairs() {
 				var name string
 				switch key := pair.Key.(type) {
 				case *object.String:
@@ -1970,7 +2336,7 @@ func (e *Task) patternMatches(
 			if err != nil {
 				return false, err
 			}
-			hashable, ok := keyObj.(object.Hashable)
+			hashable, ok := object.HashableKey(keyObj)
 			if !ok {
 				return false, fmt.Errorf("unusable as map key: %s", keyObj.Type())
 			}
@@ -2000,13 +2366,13 @@ func (e *Task) patternMatches(
 					return false, err
 				}
 			} else {
-				copiedPairs := make(map[object.MapKey]object.MapPair)
-				for mapKey, pair := range mapObj.Pairs {
-					if !usedKeys[mapKey] {
-						copiedPairs[mapKey] = pair
+				rest := &object.Map{Pairs: make(map[object.MapKey]object.MapPair)}
+				for _, pair := range mapObj.OrderedPairs() {
+					if mapKey := pair.Key.(object.Hashable).MapKey(); !usedKeys[mapKey] {
+						rest.PutPair(mapKey, pair)
 					}
 				}
-				_, err := e.patternMatches(p.Spread, &object.Map{Pairs: copiedPairs}, isConstant, isExport, isImport, pinEnv)
+				_, err := e.patternMatches(p.Spread, rest, isConstant, isExport, isImport, pinEnv)
 				if err != nil {
 					return false, err
 				}
@@ -2075,6 +2441,29 @@ func (e *Task) patternMatches(
 			}
 		}
 
+		// If a spread pattern is used, collect the unnamed fields into a map in schema order
+		if p.Spread != nil {
+			named := make(map[string]bool, len(p.Fields))
+			for _, field := range p.Fields {
+				named[field.Name] = true
+			}
+			rest := &object.Map{Pairs: make(map[object.MapKey]object.MapPair)}
+			for _, field := range schema.Fields {
+				if named[field.Name] {
+					continue
+				}
+				val, ok := structVal.Fields[field.Name]
+				if !ok {
+					val = object.NIL
+				}
+				rest.Put(object.InternSymbol(field.Name), val)
+			}
+			_, err := e.patternMatches(p.Spread, rest, isConstant, isExport, isImport, pinEnv)
+			if err != nil {
+				return false, err
+			}
+		}
+
 		for name, binding := range scoped.Bindings {
 			value, _ := scoped.Get(name)
 			if binding.IsMutable {
@@ -2147,7 +2536,7 @@ func (e *Task) patternMatchesList(
 
 	for i, elemPattern := range listPattern.Elements {
 		if spread, isSpread := elemPattern.(*ast.SpreadPattern); isSpread {
-			matched, err := e.patternMatches(spread, &object.List{Elements: list.Elements[i:]}, isConstant, isExport, isImport, pinEnv)
+			matched, err := e.patternMatches(spread, &object.List{Elements: list.Elements[i:len(list.Elements):len(list.Elements)]}, isConstant, isExport, isImport, pinEnv)
 			if err != nil || !matched {
 				e.PopEnv(nil)
 				return false, err
@@ -2183,6 +2572,33 @@ func (e *Task) patternMatchesList(
 	return true, nil
 }
 
+// listLengthMismatch explains why a list destructuring binding failed when the list has the wrong
+// number of elements for the pattern.
+func listLengthMismatch(pattern ast.MatchPattern, value object.Object) (string, bool) {
+	listPattern, ok := pattern.(*ast.ListPattern)
+	if !ok {
+		return "", false
+	}
+	list, ok := value.(*object.List)
+	if !ok {
+		return "", false
+	}
+
+	want := len(listPattern.Elements)
+	if want > 0 {
+		if _, isSpread := listPattern.Elements[want-1].(*ast.SpreadPattern); isSpread {
+			if len(list.Elements) < want-1 {
+				return fmt.Sprintf("expected list of at least %d elements, got %d", want-1, len(list.Elements)), true
+			}
+			return "", false
+		}
+	}
+	if len(list.Elements) != want {
+		return fmt.Sprintf("expected list of length %d, got %d", want, len(list.Elements)), true
+	}
+	return "", false
+}
+
 func (e *Task) patternMatchesBytes(
 	env *object.Environment,
 	listPattern *ast.ListPattern,
@@ -2211,7 +2627,7 @@ func (e *Task) patternMatchesBytes(
 
 	for i, elemPattern := range listPattern.Elements {
 		if spread, isSpread := elemPattern.(*ast.SpreadPattern); isSpread {
-			matched, err := e.patternMatches(spread, &object.Bytes{Value: bytes.Value[i:]}, isConstant, isExport, isImport, pinEnv)
+			matched, err := e.patternMatches(spread, &object.Bytes{Value: bytes.Value[i:len(bytes.Value):len(bytes.Value)]}, isConstant, isExport, isImport, pinEnv)
 			if err != nil || !matched {
 				e.PopEnv(nil)
 				return false, err
@@ -2247,50 +2663,51 @@ func (e *Task) patternMatchesBytes(
 	return true, nil
 }
 
-// evaluatePatternAsCondition evaluates patterns as conditions for valueless match
-func (e *Task) evaluatePatternAsCondition(pattern ast.MatchPattern) bool {
+// evaluatePatternAsCondition evaluates patterns as conditions for valueless match.
+// An error raised while evaluating a condition is returned rather than read as false.
+func (e *Task) evaluatePatternAsCondition(pattern ast.MatchPattern) (bool, object.Object) {
 	switch p := pattern.(type) {
 	case *ast.BindingPattern:
 		return e.evaluatePatternAsCondition(p.Pattern)
 	case *ast.WildcardPattern:
 		// Wildcard always matches
-		return true
+		return true, nil
 
 	case *ast.LiteralPattern:
 		// Evaluate the literal and check if truthy
 		result := e.Eval(p.Value)
 		if e.isError(result) {
-			return false
+			return false, result
 		}
-		return e.isTruthy(result)
+		return e.isTruthy(result), nil
 
 	case *ast.IdentifierPattern:
 		// Look up identifier and check if truthy
-		value, ok := e.CurrentEnv().Get(p.Value.Value)
-		if !ok {
-			return false
-		}
-		value = e.resolveValue(0, value)
+		value := e.evalIdentifier(p.Value)
 		if e.isError(value) {
-			return false
+			return false, value
 		}
-		return e.isTruthy(value)
+		return e.isTruthy(value), nil
 
 	case *ast.MultiPattern:
 		// Check if any subpattern is truthy
 		for _, subPattern := range p.Patterns {
-			if e.evaluatePatternAsCondition(subPattern) {
-				return true
+			matched, errObj := e.evaluatePatternAsCondition(subPattern)
+			if matched || errObj != nil {
+				return matched, errObj
 			}
 		}
-		return false
+		return false, nil
 	}
 
-	return false
+	return false, nil
 }
 
-// objectsEqual compares two objects for equality
+// objectsEqual compares two objects for equality, functions by identity
 func (e *Task) objectsEqual(a, b object.Object) bool {
+	if isFunctionValue(a) || isFunctionValue(b) {
+		return object.SameFunction(a, b)
+	}
 	if a.Type() != b.Type() {
 		return false
 	}
@@ -2353,12 +2770,24 @@ func (e *Task) objectsEqual(a, b object.Object) bool {
 
 		return true
 
+	case *object.Set:
+		other := b.(*object.Set)
+		if aVal.Len() != other.Len() {
+			return false
+		}
+		for _, el := range aVal.Elements() {
+			if !other.Has(el.(object.Hashable)) {
+				return false
+			}
+		}
+		return true
+
 	case *object.StructValue:
 		other := b.(*object.StructValue)
-		if aVal.Schema != other.Schema {
+		if aVal.Schema == nil || other.Schema == nil {
 			return false
 		}
-		if aVal.Schema == nil {
+		if aVal.Schema != other.Schema && !sameAnonStructFields(aVal.Schema, other.Schema) {
 			return false
 		}
 		for _, field := range aVal.Schema.Fields {
@@ -2380,10 +2809,37 @@ func (e *Task) objectsEqual(a, b object.Object) bool {
 	return false
 }
 
+// isFunctionValue reports whether obj is a function, which compares by identity.
+func isFunctionValue(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Foreign, *object.FunctionGroup:
+		return true
+	}
+	return false
+}
+
+func isAnonStruct(obj object.Object) bool {
+	sv, ok := obj.(*object.StructValue)
+	return ok && sv.Schema != nil && sv.Schema.Anonymous
+}
+
+// sameAnonStructFields reports whether two anonymous struct schemas declare the same field names.
+func sameAnonStructFields(a, b *object.StructSchema) bool {
+	if !a.Anonymous || !b.Anonymous || len(a.Fields) != len(b.Fields) {
+		return false
+	}
+	for _, field := range a.Fields {
+		if _, ok := b.FieldIndex[field.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (e *Task) evalMapIndexExpression(pos int, obj, index object.Object) object.Object {
 	mapObj := obj.(*object.Map)
 
-	key, ok := index.(object.Hashable)
+	key, ok := object.HashableKey(index)
 	if !ok {
 		return e.newErrorfWithPos(pos, "unusable as map key: %s", index.Type())
 	}
@@ -2486,7 +2942,7 @@ func (e *Task) evalIndexExpression(pos int, left, index object.Object) object.Ob
 				return e.evalByteSlice(arr.Value, slice)
 			}
 		}
-		return e.evalByteIndexExpression(left, index)
+		return e.evalByteIndexExpression(pos, left, index)
 	case left.Type() == object.MAP_OBJ:
 		return e.evalMapIndexExpression(pos, left, index)
 	case left.Type() == object.STRUCT_OBJ:
@@ -2530,13 +2986,13 @@ func (e *Task) evalListIndexExpression(pos int, list, index object.Object) objec
 	}
 
 	if idx < 0 || idx > max {
-		return object.NIL
+		return e.indexOutOfRange(pos, "list", num.Value.ToInt64(), len(listObject.Elements))
 	}
 
 	return listObject.Elements[idx]
 }
 
-func (e *Task) evalByteIndexExpression(list, index object.Object) object.Object {
+func (e *Task) evalByteIndexExpression(pos int, list, index object.Object) object.Object {
 	bytesObject := list.(*object.Bytes)
 	idx := index.(*object.Number).Value.ToInt64()
 	max := int64(len(bytesObject.Value) - 1)
@@ -2546,7 +3002,7 @@ func (e *Task) evalByteIndexExpression(list, index object.Object) object.Object
 	}
 
 	if idx < 0 || idx > max {
-		return object.NIL
+		return e.indexOutOfRange(pos, "bytes", index.(*object.Number).Value.ToInt64(), len(bytesObject.Value))
 	}
 
 	return &object.Number{Value: dec64.FromInt(int(bytesObject.Value[idx]))}
@@ -2569,7 +3025,7 @@ func (e *Task) evalStringIndexExpression(pos int, str, index object.Object) obje
 	}
 
 	if idx < 0 || idx > max {
-		return object.NIL
+		return e.indexOutOfRange(pos, "string", num.Value.ToInt64(), len(runes))
 	}
 
 	return &object.String{Value: string(runes[idx])}
@@ -2624,11 +3080,11 @@ func (e *Task) computeSliceIndices(length int, slice *object.Slice) (int, int, i
 	if end < 0 {
 		end += length
 	}
-	if start < 0 {
-		start = 0
-	}
-	if end > length {
-		end = length
+	// Clamp both bounds to [0, length]; an inverted range is empty
+	start = min(max(start, 0), length)
+	end = min(max(end, 0), length)
+	if start > end {
+		start = end
 	}
 	if step <= 0 {
 		// todo error on 0, consider negative step
@@ -2708,7 +3164,11 @@ func (e *Task) evalSpawnExpression(node *ast.SpawnExpression) object.Object {
 			panic("task environment stack not empty after evaluation")
 		}
 
-		// Check for ANY error type to trigger fail-fast
+		// Check for ANY error type to trigger fail-fast. A cancelled task's error is the
+		// cancellation itself, or the unwinding it caused, and is not a failure of its own
+		if taskEval.wasCancelled() {
+			return
+		}
 		if taskEval.Err != nil && !taskEval.Observed {
 			nurseryScope.NoteChildFailure(taskEval, taskEval.Err)
 		} else if e.isError(result) {
@@ -2760,6 +3220,48 @@ func (e *Task) applyTagsIfPresent(tags []*ast.Tag, val object.Object) object.Obj
 	return val
 }
 
+// memoizeIfTagged replaces a function declared with @memoize by a caching wrapper. This has to
+// happen before the value is bound so recursive calls by name also go through the cache.
+func (e *Task) memoizeIfTagged(tags []*ast.Tag, val object.Object) object.Object {
+	fn, ok := val.(*object.Function)
+	if !ok {
+		return val
+	}
+	for _, tag := range tags {
+		if tag.Name == object.MEMOIZE_TAG {
+			return foreign.Memoize(fn)
+		}
+	}
+	return val
+}
+
+// defineOperatorIfTagged binds a function declared with @operator("<>") under
+// the operator's spelling as well, which is where `a <> b` looks it up. The
+// operator is exported along with the function.
+func (e *Task) defineOperatorIfTagged(pos int, tags []*ast.Tag, val object.Object, isExported bool) object.Object {
+	for _, tag := range tags {
+		if tag.Name != object.OPERATOR_TAG {
+			continue
+		}
+		var op *ast.StringLiteral
+		if len(tag.Args) == 1 {
+			op, _ = tag.Args[0].(*ast.StringLiteral)
+		}
+		if op == nil || !lexer.IsCustomOperator(op.Value) {
+			return e.newErrorWithPos(pos, "@operator expects one string of operator characters <>=!+-*/%&|^~?$ that is not a built-in operator")
+		}
+		switch val.(type) {
+		case *object.Function, *object.Foreign, *object.FunctionGroup:
+		default:
+			return e.newErrorfWithPos(pos, "@operator(%q) must tag a function, got %s", op.Value, val.Type())
+		}
+		if _, err := e.CurrentEnv().Define(op.Value, val, isExported, false); err != nil {
+			return e.newErrorWithPos(pos, err.Error())
+		}
+	}
+	return nil
+}
+
 func (e *Task) applyDocIfPresent(pattern ast.MatchPattern, doc string, hasDoc bool) {
 	if !hasDoc {
 		return
@@ -2782,6 +3284,8 @@ func (e *Task) applyDocToPattern(pattern ast.MatchPattern, doc string, env *obje
 		}
 	case *ast.IdentifierPattern:
 		env.SetLocalDoc(p.Value.Value, doc)
+	case *ast.StringConcatPattern:
+		e.applyDocToPattern(p.Rest, doc, env)
 	case *ast.SpreadPattern:
 		if p.Value != nil {
 			env.SetLocalDoc(p.Value.Value, doc)
@@ -2806,6 +3310,9 @@ func (e *Task) applyDocToPattern(pattern ast.MatchPattern, doc string, env *obje
 				e.applyDocToPattern(field.Pattern, doc, env)
 			}
 		}
+		if p.Spread != nil {
+			e.applyDocToPattern(p.Spread, doc, env)
+		}
 	case *ast.MultiPattern, *ast.LiteralPattern, *ast.WildcardPattern, *ast.AllPattern, *ast.PinnedIdentifierPattern:
 		return
 	}
@@ -2842,7 +3349,11 @@ func (th *Task) Inspect() string {
 func (th *Task) Complete(res object.Object) {
 	th.mu.Lock()
 	defer th.mu.Unlock()
+	th.settle(res)
+}
 
+// settle records res and closes Done, the caller holds th.mu.
+func (th *Task) settle(res object.Object) {
 	if th.IsFinished {
 		return
 	}
@@ -2869,5 +3380,17 @@ func (th *Task) Cancel(cause *object.RuntimeError, reason string) {
 		Cause:   cause,
 	}
 
-	th.Complete(rt)
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	if !th.IsFinished {
+		th.cancelled = true
+	}
+	th.settle(rt)
+}
+
+// wasCancelled reports whether the task was settled by Cancel rather than by its own result.
+func (th *Task) wasCancelled() bool {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	return th.cancelled
 }
@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"context"
+	"time"
+)
+
+// taskContext exposes a task's cancellation to foreign functions as a
+// context.Context. It is done once the task settles, which for a task still
+// running a foreign call means it was cancelled: by a timeout, a failing sibling
+// or its nursery exiting.
+type taskContext struct {
+	task *Task
+}
+
+// Context returns a context that is cancelled with the task, so long-running
+// foreign functions can stop waiting once their result is no longer wanted.
+func (e *Task) Context() context.Context {
+	return taskContext{task: e}
+}
+
+func (c taskContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (c taskContext) Done() <-chan struct{} { return c.task.Done }
+
+func (c taskContext) Err() error {
+	if c.task.Done == nil {
+		return nil
+	}
+	select {
+	case <-c.task.Done:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+func (c taskContext) Value(key any) any { return nil }
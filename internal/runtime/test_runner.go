@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"slug/internal/object"
+	"sort"
+)
+
+// TaggedBinding is a module level binding whose value carries a given tag.
+type TaggedBinding struct {
+	Name  string
+	Value object.Object
+}
+
+// TestResult is the outcome of running a single @test function. Err is nil when the test passed.
+type TestResult struct {
+	Name string
+	Err  object.Object
+}
+
+func (r TestResult) Passed() bool {
+	return r.Err == nil
+}
+
+// TaggedBindings returns the bindings of a loaded module whose values carry tag, sorted by name.
+// Imported bindings are skipped so a module only reports what it declares itself.
+func (r *Runtime) TaggedBindings(moduleName string, tag string) ([]TaggedBinding, error) {
+	module, ok := r.Modules[moduleName]
+	if !ok || module.Env == nil {
+		return nil, fmt.Errorf("module %s is not loaded", moduleName)
+	}
+
+	var tagged []TaggedBinding
+	for name, binding := range module.Env.Bindings {
+		if binding.Meta.IsImport {
+			continue
+		}
+		if t, ok := binding.Value.(object.Taggable); ok && t.HasTag(tag) {
+			tagged = append(tagged, TaggedBinding{Name: name, Value: binding.Value})
+		}
+	}
+	sort.Slice(tagged, func(i, j int) bool { return tagged[i].Name < tagged[j].Name })
+	return tagged, nil
+}
+
+// RunTests calls every nullary @test function declared in moduleName. A test fails when it
+// throws or returns an error.
+func (e *Task) RunTests(moduleName string) ([]TestResult, error) {
+	tagged, err := e.Runtime.TaggedBindings(moduleName, object.TEST_TAG)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TestResult
+	for _, tb := range tagged {
+		if !isNullary(tb.Value) {
+			continue
+		}
+		out := e.ApplyFunction(0, tb.Name, tb.Value, nil, nil)
+		switch out := out.(type) {
+		case *object.RuntimeError, *object.Error:
+			results = append(results, TestResult{Name: tb.Name, Err: out})
+		default:
+			results = append(results, TestResult{Name: tb.Name})
+		}
+	}
+	return results, nil
+}
+
+// WriteTestReport writes one line per test followed by a summary and returns the number of failures.
+func WriteTestReport(w io.Writer, results []TestResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(w, "  [PASS] %s()\n", r.Name)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "  [FAIL] %s(): %s\n", r.Name, testFailureMessage(r.Err))
+	}
+	fmt.Fprintf(w, "\nTests run: %d, Passed: %d, Failed: %d\n", len(results), len(results)-failed, failed)
+	return failed
+}
+
+func testFailureMessage(err object.Object) string {
+	if re, ok := err.(*object.RuntimeError); ok && re.Payload != nil {
+		return re.Payload.Inspect()
+	}
+	return err.Inspect()
+}
+
+func isNullary(fn object.Object) bool {
+	switch fn := fn.(type) {
+	case *object.FunctionGroup:
+		for sig := range fn.Functions {
+			if sig.Min == 0 {
+				return true
+			}
+		}
+	case *object.Function:
+		return fn.Signature.Min == 0
+	}
+	return false
+}
@@ -29,12 +29,20 @@ const (
 	APPEND_ITEM  = ":+"
 	PREPEND_ITEM = "+:"
 
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+	PERCENT_ASSIGN  = "%="
+
 	INTERPOLATION_START = "{{"
 	INTERPOLATION_END   = "}}"
 
 	MATCH_KEYS_EXACT = "{|"
 	MATCH_KEYS_CLOSE = "|}"
 
+	ANON_STRUCT = "#{"
+
 	LT    = "<"
 	LT_EQ = "<="
 	GT    = ">"
@@ -47,8 +55,9 @@ const (
 	SHIFT_LEFT  = "<<"
 	SHIFT_RIGHT = ">>"
 
-	LOGICAL_AND = "&&"
-	LOGICAL_OR  = "||"
+	LOGICAL_AND  = "&&"
+	LOGICAL_OR   = "||"
+	NIL_COALESCE = "??"
 
 	EQ     = "=="
 	NOT_EQ = "!="
@@ -57,6 +66,9 @@ const (
 	ELLIPSIS        = "..."
 	NOT_IMPLEMENTED = "???"
 	CALL_CHAIN      = "/>"
+	OPTIONAL_CHAIN  = "?."
+
+	CUSTOM_OPERATOR = "CUSTOM_OPERATOR" // a binary operator declared with @operator, such as <>
 
 	// Delimiters
 	PERIOD    = "."
@@ -100,6 +112,7 @@ type Token struct {
 	Type     TokenType
 	Literal  string
 	Position int // the src index of the token
+	End      int // the src index just past the token
 }
 
 var keywords = map[string]TokenType{
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -11,13 +12,29 @@ import (
 type Configuration struct {
 	Version      string
 	RootPath     string
+	ModulePaths  []string // Extra module roots searched after RootPath and before $SLUG_HOME/lib
 	SlugHome     string
 	Argv         []string
 	DebugJsonAST bool
 	DebugTxtAST  bool
 	DefaultLimit int
+	Seed         int64  // Seed for the runtime random source used by uuid, 0 seeds from the clock
 	MainModule   string // The entry point module name (e.g., "slug.server")
 	Store        *ConfigStore
+
+	// AllowedBuiltins restricts which builtins (print, import, ...) a program may use, nil allows all
+	AllowedBuiltins []string
+	// MaxSteps bounds the number of evaluation steps across all tasks, 0 means unlimited
+	MaxSteps int64
+	// Deadline and Timeout bound the wall-clock time of a run, the earlier of the two applies
+	Deadline time.Time
+	Timeout  time.Duration
+	// DisplayPrecision rounds numbers to this many decimal places in print and println output, 0 shows them in full
+	DisplayPrecision int
+	// StrictIndex makes an out of range list, string or bytes index an error instead of nil
+	StrictIndex bool
+	// StrictMatch makes a match expression with no matching case an error instead of nil
+	StrictMatch bool
 }
 
 type ConfigStore struct {
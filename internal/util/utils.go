@@ -6,6 +6,8 @@ import (
 	"strings"
 )
 
+// GetLineAndColumn converts a byte offset into src, as recorded on tokens, into a 1-based line
+// and a 1-based column counted in runes.
 func GetLineAndColumn(src string, pos int) (line int, column int) {
 	line = 1
 	column = 1
@@ -23,6 +25,20 @@ func GetLineAndColumn(src string, pos int) (line int, column int) {
 	return
 }
 
+// FormatDiagnostic renders msg followed by the file location of pos and the source lines leading up
+// to it, with a marker under the offending column. Parse and runtime errors share this layout, so
+// hosts embedding slug can use it to render their own diagnostics the same way.
+func FormatDiagnostic(src, path string, pos int, msg string) string {
+	line, col := GetLineAndColumn(src, pos)
+
+	var out bytes.Buffer
+	out.WriteString(msg)
+	out.WriteString("\n")
+	out.WriteString(fmt.Sprintf("    --> %s:%d:%d\n", path, line, col))
+	out.WriteString(GetContextLines(src, line, col))
+	return out.String()
+}
+
 // GetContextLines extracts and formats context lines around an error position
 func GetContextLines(src string, errorLine, errorCol int) string {
 	var result bytes.Buffer
@@ -62,8 +78,11 @@ func GetContextLines(src string, errorLine, errorCol int) string {
 			// Error line with arrow
 			margin := fmt.Sprintf("  >  %3d | ", lineNum)
 			result.WriteString(fmt.Sprintf("%s%s\n", margin, lineContent))
+			// errorCol counts runes, so the marker is aligned by rune rather than byte
+			runes := []rune(lineContent)
+			prefix := string(runes[:min(errorCol-1, len(runes))])
 			result.WriteString(fmt.Sprintf("%s^ unexpected here",
-				replaceVisibleWithSpaces(margin+lineContent[:errorCol-1])))
+				replaceVisibleWithSpaces(margin+prefix)))
 		} else {
 			// Context line
 			result.WriteString(fmt.Sprintf("     %3d | %s\n", lineNum, lineContent))
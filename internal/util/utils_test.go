@@ -0,0 +1,44 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatDiagnostic(t *testing.T) {
+	src := "val a = 1\nval b = 2\nval c = a +\n"
+	pos := len("val a = 1\nval b = 2\nval c = ")
+
+	got := FormatDiagnostic(src, "demo.slug", pos, "Error: something went wrong")
+	want := "Error: something went wrong\n" +
+		"    --> demo.slug:3:9\n" +
+		"       1 | val a = 1\n" +
+		"       2 | val b = 2\n" +
+		"  >    3 | val c = a +\n" +
+		"                   ^ unexpected here"
+
+	if got != want {
+		t.Fatalf("unexpected diagnostic.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestColumnsCountRunes(t *testing.T) {
+	src := "val a = 1\nval s = \"héllo wörld\" + )\n"
+	pos := strings.Index(src, ")")
+
+	line, col := GetLineAndColumn(src, pos)
+	if line != 2 || col != 25 {
+		t.Fatalf("expected 2:25, got %d:%d", line, col)
+	}
+
+	got := FormatDiagnostic(src, "demo.slug", pos, "ParseError: unexpected )")
+	want := "ParseError: unexpected )\n" +
+		"    --> demo.slug:2:25\n" +
+		"       1 | val a = 1\n" +
+		"  >    2 | val s = \"héllo wörld\" + )\n" +
+		"                                   ^ unexpected here"
+
+	if got != want {
+		t.Fatalf("unexpected diagnostic.\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}